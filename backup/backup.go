@@ -0,0 +1,390 @@
+// Package backup snapshots secrets into encrypted archives and restores them into any
+// store.SecretStore
+package backup
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"filippo.io/age"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/rs/zerolog"
+)
+
+// FormatVersion is the version of the archive format
+const FormatVersion = 1
+
+// Version is a version of a secret with a version stage
+type Version struct {
+	VersionID   string     `json:"versionId"`
+	Stages      []string   `json:"stages"`
+	CreatedDate *time.Time `json:"createdDate,omitempty"`
+	Value       string     `json:"value"`
+}
+
+// Secret is the snapshot of a secret. Only the versions with a version stage are kept
+type Secret struct {
+	Name           string            `json:"name"`
+	Tags           map[string]string `json:"tags"`
+	KmsKeyID       string            `json:"kmsKeyId,omitempty"`       // KMS key ARN. empty for the aws/secretsmanager key
+	ReplicaRegions []string          `json:"replicaRegions,omitempty"` // regions the secret is replicated to
+	Versions       []Version         `json:"versions"`                 // oldest first
+}
+
+// Archive is the snapshot of the secrets matching TagFilters
+type Archive struct {
+	FormatVersion int               `json:"formatVersion"`
+	CreatedDate   time.Time         `json:"createdDate"`
+	TagFilters    map[string]string `json:"tagFilters"`
+	Secrets       []Secret          `json:"secrets"`
+	Checksum      string            `json:"checksum"` // sha256 of the JSON encoded secrets
+}
+
+// checksum returns the sha256 of the JSON encoded secrets
+func (a Archive) checksum() (string, error) {
+	content, err := json.Marshal(a.Secrets)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Verify returns an error if the checksum doesn't match the secrets
+func (a Archive) Verify() error {
+	if a.FormatVersion != FormatVersion {
+		return fmt.Errorf("unsupported archive format version: %d", a.FormatVersion)
+	}
+	sum, err := a.checksum()
+	if err != nil {
+		return err
+	}
+	if sum != a.Checksum {
+		return fmt.Errorf("archive checksum mismatch: expected %s, got %s", a.Checksum, sum)
+	}
+	return nil
+}
+
+// Snapshot reads the secrets whose tags match every key/value in tagFilters with their tags,
+// KMS key, replica regions and the values of their staged versions. Transient errors are
+// retried with store.DefaultRetryPolicy
+func Snapshot(st store.SecretStore, tagFilters map[string]string, log *zerolog.Logger) (archive Archive, err error) {
+	ctx := context.Background()
+	retryStore := store.NewRetryStore(st, store.DefaultRetryPolicy)
+	descriptions, err := retryStore.List(ctx, tagFilters)
+	if err != nil {
+		return archive, fmt.Errorf("error listing secrets: %w", err)
+	}
+	sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+	archive = Archive{
+		FormatVersion: FormatVersion,
+		CreatedDate:   time.Now().UTC(),
+		TagFilters:    tagFilters,
+		Secrets:       []Secret{},
+	}
+	for _, description := range descriptions {
+		log.Debug().Msgf("backing up secret: %s", description.Name)
+		// List doesn't return the replicas
+		details, err := retryStore.Describe(ctx, description.Name)
+		if err != nil {
+			return archive, fmt.Errorf("error describing %s: %w", description.Name, err)
+		}
+		versions, err := retryStore.Versions(ctx, description.Name)
+		if err != nil {
+			return archive, fmt.Errorf("error listing versions of %s: %w", description.Name, err)
+		}
+		store.SortVersions(versions)
+		secret := Secret{
+			Name:           description.Name,
+			Tags:           description.Tags,
+			KmsKeyID:       details.KmsKeyID,
+			ReplicaRegions: details.ReplicaRegions(),
+			Versions:       []Version{},
+		}
+		for _, version := range versions {
+			if len(version.Stages) == 0 {
+				continue
+			}
+			value, err := retryStore.GetVersion(ctx, description.Name, version.VersionID, "")
+			if err != nil {
+				return archive, fmt.Errorf("error getting version %s of %s: %w", version.VersionID, description.Name, err)
+			}
+			secret.Versions = append(secret.Versions, Version{
+				VersionID:   version.VersionID,
+				Stages:      version.Stages,
+				CreatedDate: version.CreatedDate,
+				Value:       value,
+			})
+		}
+		archive.Secrets = append(archive.Secrets, secret)
+	}
+	archive.Checksum, err = archive.checksum()
+	return archive, err
+}
+
+// Write writes the archive as gzipped JSON encrypted to the recipients
+func Write(w io.Writer, archive Archive, recipients ...age.Recipient) error {
+	encrypted, err := age.Encrypt(w, recipients...)
+	if err != nil {
+		return err
+	}
+	compressed := gzip.NewWriter(encrypted)
+	if err = json.NewEncoder(compressed).Encode(archive); err != nil {
+		return err
+	}
+	if err = compressed.Close(); err != nil {
+		return err
+	}
+	return encrypted.Close()
+}
+
+// Read decrypts an archive written by Write with one of the identities and verifies its checksum
+func Read(r io.Reader, identities ...age.Identity) (archive Archive, err error) {
+	decrypted, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return archive, err
+	}
+	compressed, err := gzip.NewReader(decrypted)
+	if err != nil {
+		return archive, err
+	}
+	if err = json.NewDecoder(compressed).Decode(&archive); err != nil {
+		return archive, fmt.Errorf("error decoding archive: %w", err)
+	}
+	return archive, archive.Verify()
+}
+
+// ChecksumPath returns the path of the checksum file of an archive
+func ChecksumPath(path string) string {
+	return path + ".sha256"
+}
+
+// fileChecksum returns the sha256 of a file
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// WriteChecksumFile writes the sha256 of the encrypted archive in the sha256sum format so the
+// archive can be verified without the key
+func WriteChecksumFile(path string) error {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	line := fmt.Sprintf("%s  %s\n", sum, baseName(path))
+	return os.WriteFile(ChecksumPath(path), []byte(line), 0o600)
+}
+
+// VerifyChecksumFile returns an error if the archive doesn't match its checksum file
+func VerifyChecksumFile(path string) error {
+	content, err := os.ReadFile(ChecksumPath(path))
+	if err != nil {
+		return err
+	}
+	expected, _, _ := strings.Cut(strings.TrimSpace(string(content)), " ")
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if sum != expected {
+		return fmt.Errorf("checksum mismatch: %s: expected %s, got %s", path, expected, sum)
+	}
+	return nil
+}
+
+// baseName returns the last element of a slash separated path
+func baseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+// Result is the outcome of restoring one secret
+type Result struct {
+	SecretID string
+	Action   store.Action // create, update or skip. empty when Err is set
+	Err      error
+}
+
+// Results are the outcomes of a restore
+type Results []Result
+
+// Count returns the number of results with the action
+func (r Results) Count(action store.Action) (count int) {
+	for _, result := range r {
+		if result.Err == nil && result.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of secrets that couldn't be restored
+func (r Results) Failed() (count int) {
+	for _, result := range r {
+		if result.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// restoreOrder returns the versions in the order they're written: AWSPREVIOUS second to last
+// and AWSCURRENT last so Put leaves both stages where they were. The other versions keep
+// their order
+func restoreOrder(versions []Version) []Version {
+	rank := func(version Version) int {
+		for _, stage := range version.Stages {
+			switch stage {
+			case store.StageCurrent:
+				return 2
+			case store.StagePrevious:
+				return 1
+			}
+		}
+		return 0
+	}
+	result := append([]Version(nil), versions...)
+	sort.SliceStable(result, func(i, j int) bool { return rank(result[i]) < rank(result[j]) })
+	return result
+}
+
+// restoreSecret writes the versions of a secret with its KMS key, replicates it to its replica
+// regions other than the region of st and attaches the stages other than AWSCURRENT and
+// AWSPREVIOUS to the new versions
+func restoreSecret(ctx context.Context, st store.SecretStore, secret Secret, exists bool) error {
+	versions := restoreOrder(secret.Versions)
+	if len(versions) == 0 {
+		return fmt.Errorf("%w: no versions to restore: %s", store.ErrInvalidRequest, secret.Name)
+	}
+	if exists && secret.KmsKeyID != "" {
+		// Encrypt the secret with the KMS key before the versions are stored
+		if err := st.SetKMSKey(ctx, secret.Name, secret.KmsKeyID); err != nil {
+			return err
+		}
+	}
+	newIDs := map[string]string{}
+	for i, version := range versions {
+		var versionID string
+		var err error
+		if i == 0 && !exists {
+			versionID, err = st.Create(ctx, secret.Name, version.Value, secret.Tags, secret.KmsKeyID)
+		} else {
+			versionID, err = st.Put(ctx, secret.Name, version.Value)
+		}
+		if err != nil {
+			return err
+		}
+		newIDs[version.VersionID] = versionID
+	}
+	if exists {
+		if err := st.Tag(ctx, secret.Name, secret.Tags); err != nil {
+			return err
+		}
+	}
+	// An archive restored to one of its replica regions isn't replicated to that region
+	region := store.Region(st)
+	replicaRegions := slices.DeleteFunc(slices.Clone(secret.ReplicaRegions), func(replica string) bool {
+		return replica == region
+	})
+	if len(replicaRegions) > 0 {
+		if err := store.ReconcileReplicas(ctx, st, secret.Name, replicaRegions); err != nil {
+			return err
+		}
+	}
+	hasPrevious := false
+	for _, version := range versions {
+		for _, stage := range version.Stages {
+			if stage == store.StagePrevious {
+				hasPrevious = true
+			}
+			if stage == store.StageCurrent || stage == store.StagePrevious {
+				continue
+			}
+			description, err := st.Describe(ctx, secret.Name)
+			if err != nil {
+				return err
+			}
+			err = st.UpdateVersionStage(ctx, secret.Name, stage, newIDs[version.VersionID], description.StageVersionID(stage))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if hasPrevious {
+		return nil
+	}
+	// Put moved AWSPREVIOUS to a version that didn't have it
+	description, err := st.Describe(ctx, secret.Name)
+	if err != nil {
+		return err
+	}
+	if previous := description.StageVersionID(store.StagePrevious); previous != "" {
+		return st.UpdateVersionStage(ctx, secret.Name, store.StagePrevious, "", previous)
+	}
+	return nil
+}
+
+// Restore writes the secrets of the archive. Secrets that don't exist are created with their
+// tags. Existing secrets are skipped unless overwrite is true. The staged versions are written
+// as new versions, so the version IDs change but the values and stages are restored
+func Restore(st store.SecretStore, archive Archive, overwrite bool, log *zerolog.Logger) (Results, error) {
+	if err := archive.Verify(); err != nil {
+		return nil, err
+	}
+	ctx := context.Background()
+	retryStore := store.NewRetryStore(st, store.DefaultRetryPolicy)
+	results := make(Results, len(archive.Secrets))
+	for i, secret := range archive.Secrets {
+		result := &results[i]
+		result.SecretID = secret.Name
+		exists, err := store.Exists(ctx, retryStore, secret.Name)
+		switch {
+		case err != nil:
+			result.Err = err
+		case exists && !overwrite:
+			result.Action = store.ActionSkip
+			log.Info().Msgf("secret exists, run with -overwrite to restore it: %s", secret.Name)
+			continue
+		case exists:
+			result.Action = store.ActionUpdate
+		default:
+			result.Action = store.ActionCreate
+		}
+		if result.Err == nil {
+			result.Err = restoreSecret(ctx, retryStore, secret, exists)
+		}
+		if result.Err != nil {
+			result.Action = ""
+			log.Error().Err(result.Err).Msgf("error restoring secret: %s", secret.Name)
+			continue
+		}
+		log.Info().Msgf("restored secret (%s): %s", result.Action, secret.Name)
+	}
+	if failed := results.Failed(); failed > 0 {
+		return results, fmt.Errorf("%d of %d secrets failed", failed, len(results))
+	}
+	return results, nil
+}
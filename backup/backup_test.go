@@ -0,0 +1,223 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+// testKMSKeyID is the KMS key of the managed test secret
+const testKMSKeyID = "arn:aws:kms:us-east-1:111122223333:key/testenv"
+
+// testSecrets creates a secret with AWSCURRENT, AWSPREVIOUS and a custom stage, an unstaged
+// version, a KMS key and a replica, and a secret with another source
+func testSecrets(t *testing.T) *store.MemoryStore {
+	t.Helper()
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	managed := map[string]string{"Source": "secret-hoard", "ResourceType": "text_file", "Environment": "testenv"}
+	secretID := "text_file/testenv/my_file_type"
+	oldID, err := st.Create(ctx, secretID, "old", managed, testKMSKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = st.ReplicateRegions(ctx, secretID, []string{"us-west-2"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range []string{"unstaged", "previous", "current"} {
+		if _, err = st.Put(ctx, secretID, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = st.UpdateVersionStage(ctx, secretID, "MYSTAGE", oldID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.Create(ctx, "other/testenv/secret", "other", map[string]string{"Source": "other"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+// stagedValues returns the value of every stage of a secret
+func stagedValues(t *testing.T, st store.SecretStore, secretID string) map[string]string {
+	t.Helper()
+	ctx := context.Background()
+	versions, err := st.Versions(ctx, secretID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]string{}
+	for _, version := range versions {
+		for _, stage := range version.Stages {
+			if result[stage], err = st.GetVersion(ctx, secretID, "", stage); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return result
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	log := tools.TestLogger()
+	st := testSecrets(t)
+	secretID := "text_file/testenv/my_file_type"
+	archive, err := Snapshot(st, map[string]string{"Source": "secret-hoard"}, &log)
+	if err != nil || len(archive.Secrets) != 1 || len(archive.Secrets[0].Versions) != 3 {
+		t.Fatalf("Snapshot() = %+v, %v, want 1 secret with 3 staged versions", archive, err)
+	}
+
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err = Write(&buf, archive, identity.Recipient()); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := age.GenerateX25519Identity()
+	if _, err = Read(bytes.NewReader(buf.Bytes()), other); err == nil {
+		t.Error("Read() with another identity error = nil")
+	}
+	read, err := Read(bytes.NewReader(buf.Bytes()), identity)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := store.NewMemoryStore()
+	results, err := Restore(restored, read, false, &log)
+	if err != nil || results.Count(store.ActionCreate) != 1 {
+		t.Fatalf("Restore() = %+v, %v", results, err)
+	}
+	want := map[string]string{store.StageCurrent: "current", store.StagePrevious: "previous", "MYSTAGE": "old"}
+	if got := stagedValues(t, restored, secretID); !reflect.DeepEqual(got, want) {
+		t.Errorf("restored stages = %v, want %v", got, want)
+	}
+	description, err := restored.Describe(context.Background(), secretID)
+	if err != nil || description.Tags["Environment"] != "testenv" {
+		t.Errorf("restored tags = %v, %v", description.Tags, err)
+	}
+	if description.KmsKeyID != testKMSKeyID || !reflect.DeepEqual(description.ReplicaRegions(), []string{"us-west-2"}) {
+		t.Errorf("restored KMS key and replicas = %q, %v, want %q, [us-west-2]", description.KmsKeyID, description.ReplicaRegions(), testKMSKeyID)
+	}
+
+	// existing secrets are skipped unless overwrite is true
+	if _, err = restored.Put(context.Background(), secretID, "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if results, err = Restore(restored, read, false, &log); err != nil || results.Count(store.ActionSkip) != 1 {
+		t.Errorf("Restore() = %+v, %v, want skip", results, err)
+	}
+	if results, err = Restore(restored, read, true, &log); err != nil || results.Count(store.ActionUpdate) != 1 {
+		t.Errorf("Restore(overwrite) = %+v, %v, want update", results, err)
+	}
+	if got := stagedValues(t, restored, secretID); !reflect.DeepEqual(got, want) {
+		t.Errorf("overwritten stages = %v, want %v", got, want)
+	}
+}
+
+// regionStore is a MemoryStore in an AWS region
+type regionStore struct {
+	*store.MemoryStore
+	region string
+}
+
+// Region returns the region of the store
+func (s regionStore) Region() string {
+	return s.region
+}
+
+func TestRestoreToReplicaRegion(t *testing.T) {
+	log := tools.TestLogger()
+	archive, err := Snapshot(testSecrets(t), map[string]string{"Source": "secret-hoard"}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := regionStore{store.NewMemoryStore(), "us-west-2"}
+	if _, err = Restore(restored, archive, false, &log); err != nil {
+		t.Fatal(err)
+	}
+	description, err := restored.Describe(context.Background(), "text_file/testenv/my_file_type")
+	if err != nil || len(description.ReplicaRegions()) != 0 {
+		t.Errorf("restored replicas = %v, %v, want none", description.ReplicaRegions(), err)
+	}
+	if !reflect.DeepEqual(archive.Secrets[0].ReplicaRegions, []string{"us-west-2"}) {
+		t.Errorf("archive replicas = %v, want [us-west-2]", archive.Secrets[0].ReplicaRegions)
+	}
+}
+
+func TestRestoreChecksumMismatch(t *testing.T) {
+	log := tools.TestLogger()
+	archive, err := Snapshot(testSecrets(t), map[string]string{"Source": "secret-hoard"}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive.Secrets[0].Versions[0].Value = "tampered"
+	if _, err = Restore(store.NewMemoryStore(), archive, false, &log); err == nil {
+		t.Error("Restore() error = nil, want checksum mismatch")
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	log := tools.TestLogger()
+	archive, err := Snapshot(testSecrets(t), nil, &log)
+	if err != nil || len(archive.Secrets) != 2 {
+		t.Fatalf("Snapshot() = %+v, %v", archive, err)
+	}
+	recipient, err := age.NewScryptRecipient("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	recipient.SetWorkFactor(10)
+	path := filepath.Join(t.TempDir(), "backup.age")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = Write(file, archive, recipient); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+	if err = WriteChecksumFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyChecksumFile(path); err != nil {
+		t.Errorf("VerifyChecksumFile() error = %v", err)
+	}
+
+	passphraseFile := filepath.Join(t.TempDir(), "passphrase")
+	if err = os.WriteFile(passphraseFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	passphrase, err := ReadPassphrase(passphraseFile)
+	if err != nil || passphrase != "secret" {
+		t.Fatalf("ReadPassphrase() = %q, %v", passphrase, err)
+	}
+	identities, err := Identities("", passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(path)
+	if read, err := Read(bytes.NewReader(content), identities...); err != nil || len(read.Secrets) != 2 {
+		t.Errorf("Read() = %+v, %v", read, err)
+	}
+	if identities, err = Identities("", "wrong"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Read(bytes.NewReader(content), identities...); err == nil {
+		t.Error("Read() with the wrong passphrase error = nil")
+	}
+
+	if err = os.WriteFile(path, append(content, 0), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err = VerifyChecksumFile(path); err == nil {
+		t.Error("VerifyChecksumFile() of a changed file error = nil")
+	}
+}
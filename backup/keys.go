@@ -0,0 +1,96 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+)
+
+// PassphraseEnv is the environment variable the passphrase is read from when no passphrase
+// file is given. The passphrase is never a flag value so it doesn't end up in the shell history
+const PassphraseEnv = "SECRET_HOARD_PASSPHRASE"
+
+// ReadPassphrase returns the first line of the passphrase file or the value of PassphraseEnv
+// if path is empty. It returns an empty string if neither is set
+func ReadPassphrase(path string) (string, error) {
+	if path == "" {
+		return os.Getenv(PassphraseEnv), nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	passphrase, _, _ := strings.Cut(string(content), "\n")
+	passphrase = strings.TrimSuffix(passphrase, "\r")
+	if passphrase == "" {
+		return "", fmt.Errorf("empty passphrase file: %s", path)
+	}
+	return passphrase, nil
+}
+
+// Recipients returns the age recipients an archive is encrypted to. Each recipient is an age
+// public key (age1...) or the path of a recipients file. The passphrase is only used when no
+// recipient is given because age doesn't mix passphrases with public keys
+func Recipients(recipients []string, passphrase string) ([]age.Recipient, error) {
+	if len(recipients) == 0 {
+		if passphrase == "" {
+			return nil, fmt.Errorf("a recipient or a passphrase in %s is required", PassphraseEnv)
+		}
+		recipient, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Recipient{recipient}, nil
+	}
+	var result []age.Recipient
+	for _, value := range recipients {
+		if strings.HasPrefix(value, "age1") {
+			recipient, err := age.ParseX25519Recipient(value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, recipient)
+			continue
+		}
+		file, err := os.Open(value)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := age.ParseRecipients(file)
+		_ = file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading recipients file %s: %w", value, err)
+		}
+		result = append(result, parsed...)
+	}
+	return result, nil
+}
+
+// Identities returns the age identities an archive is decrypted with. The identities are read
+// from the identity file (as written by age-keygen) or derived from the passphrase
+func Identities(identityFile, passphrase string) ([]age.Identity, error) {
+	if identityFile == "" {
+		if passphrase == "" {
+			return nil, fmt.Errorf("an identity file or a passphrase in %s is required", PassphraseEnv)
+		}
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		return []age.Identity{identity}, nil
+	}
+	file, err := os.Open(identityFile)
+	if err != nil {
+		return nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+	identities, err := age.ParseIdentities(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading identity file %s: %w", identityFile, err)
+	}
+	return identities, nil
+}
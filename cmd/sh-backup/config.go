@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/backup"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	File           string            // archive file to write. the checksum is written to File.sha256
+	TagFilters     map[string]string // back up the secrets whose tags match every key/value
+	Recipients     []string          // age public keys or recipients files to encrypt to
+	PassphraseFile string            // file to read the passphrase from instead of SECRET_HOARD_PASSPHRASE
+	Debug          bool              // enable debug mode
+}
+
+// GetLogger returns a logger for the application
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stdout).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config, err error) {
+	tags := tools.TagFlags{}
+	recipients := tools.ListFlags{}
+	// Define flags
+	filePtr := flag.String("file", "", "Archive file to write. It must not exist")
+	flag.String("source", "secret-hoard", "Source tag to filter by")
+	flag.String("resource-type", "", "ResourceType tag to filter by ex. rdspostgres")
+	flag.String("environment", "", "Environment tag to filter by")
+	flag.Var(tags, "tag", "Tag key=value to filter by. Can be repeated")
+	flag.Var(&recipients, "recipient", "age public key (age1...) or recipients file to encrypt to. Can be repeated. Without it the archive is encrypted with the passphrase")
+	passphraseFilePtr := flag.String("passphrase-file", "", "File to read the passphrase from. Defaults to the "+backup.PassphraseEnv+" environment variable")
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	// Parse command line arguments
+	flag.Parse()
+	if err = tags.Merge(flag.CommandLine, map[string]string{
+		"source":        "Source",
+		"resource-type": "ResourceType",
+		"environment":   "Environment",
+	}); err != nil {
+		return config, err
+	}
+	config.File = *filePtr
+	config.TagFilters = tags
+	config.Recipients = recipients
+	config.PassphraseFile = *passphraseFilePtr
+	config.Debug = *debugPtr
+
+	if config.File == "" {
+		return config, errors.New("-file is required")
+	}
+	if _, err := os.Stat(config.File); err == nil {
+		return config, fmt.Errorf("file exists: %s", config.File)
+	}
+	if len(config.Recipients) > 0 && config.PassphraseFile != "" {
+		return config, errors.New("-recipient and -passphrase-file can't be used together")
+	}
+	return config, nil
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/backup"
+	"github.com/natemarks/secret-hoard/store"
+)
+
+func main() {
+	cfg, err := GetConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	passphrase, err := backup.ReadPassphrase(cfg.PassphraseFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to read passphrase")
+	}
+	recipients, err := backup.Recipients(cfg.Recipients, passphrase)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load recipients")
+	}
+	st, err := store.Default()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load secret store")
+	}
+	archive, err := backup.Snapshot(st, cfg.TagFilters, &log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("backup failed")
+	}
+	file, err := os.OpenFile(cfg.File, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to create archive")
+	}
+	err = backup.Write(file, archive, recipients...)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(cfg.File)
+		log.Fatal().Err(err).Msg("unable to write archive")
+	}
+	if err = backup.WriteChecksumFile(cfg.File); err != nil {
+		log.Fatal().Err(err).Msg("unable to write checksum file")
+	}
+	log.Info().Msgf("backed up %d secrets to %s (checksum %s)", len(archive.Secrets), cfg.File, backup.ChecksumPath(cfg.File))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	SourceProfile      string            // AWS profile to read the secrets from. empty is the default profile
+	SourceRegion       string            // AWS region to read the secrets from. empty is the profile region
+	DestinationProfile string            // AWS profile to write the secrets to
+	DestinationRegion  string            // AWS region to write the secrets to
+	SecretIDs          []string          // secrets to copy. TagFilters select them when empty
+	TagFilters         map[string]string // copy the secrets whose tags match every key/value
+	Overwrite          bool              // update destination secrets whose value, tags, replicas or KMS key differ
+	KmsKeyID           string            // KMS key of the destination secrets
+	ReplicaRegions     []string          // replica regions of the destination secrets. empty copies the source replicas
+	RestoreDeleted     bool              // restore destination secrets scheduled for deletion before updating them
+	Plan               bool              // print what would change without writing anything
+	Debug              bool              // enable debug mode
+}
+
+// GetLogger returns a logger for the application
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stdout).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config, err error) {
+	tags := tools.TagFlags{}
+	secretIDs := tools.ListFlags{}
+	// Define flags
+	sourceProfilePtr := flag.String("source-profile", "", "AWS profile to read the secrets from. Defaults to the default profile")
+	sourceRegionPtr := flag.String("source-region", "", "AWS region to read the secrets from. Defaults to the region of the profile")
+	destinationProfilePtr := flag.String("destination-profile", "", "AWS profile to write the secrets to. Defaults to the default profile")
+	destinationRegionPtr := flag.String("destination-region", "", "AWS region to write the secrets to. Defaults to the region of the profile")
+	flag.Var(&secretIDs, "secret", "Secret ID to copy. Can be repeated. The tag filters are ignored when it's set")
+	flag.String("source", "secret-hoard", "Source tag to filter by")
+	flag.String("resource-type", "", "ResourceType tag to filter by ex. rdspostgres")
+	flag.String("environment", "", "Environment tag to filter by")
+	flag.Var(tags, "tag", "Tag key=value to filter by. Can be repeated")
+	overwritePtr := flag.Bool("overwrite", false, "Update destination secrets whose value, tags, replica regions or KMS key differ")
+	kmsKeyIDPtr := flag.String("kms-key-id", "", "KMS key ARN to encrypt the destination secrets with. Defaults to the aws/secretsmanager key of new secrets")
+	replicaRegionsPtr := flag.String("replica-regions", "", "Regions to replicate the destination secrets to separated by commas ex. us-west-2,eu-west-1. Defaults to the replica regions of the source secrets")
+	restoreDeletedPtr := flag.Bool("restore-deleted", false, "Restore destination secrets that are scheduled for deletion and update them")
+	planPtr := flag.Bool("plan", false, "Print what would change without writing anything")
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	// Parse command line arguments
+	flag.Parse()
+	if err = tags.Merge(flag.CommandLine, map[string]string{
+		"source":        "Source",
+		"resource-type": "ResourceType",
+		"environment":   "Environment",
+	}); err != nil {
+		return config, err
+	}
+	config.SourceProfile = *sourceProfilePtr
+	config.SourceRegion = *sourceRegionPtr
+	config.DestinationProfile = *destinationProfilePtr
+	config.DestinationRegion = *destinationRegionPtr
+	config.SecretIDs = secretIDs
+	config.TagFilters = tags
+	config.Overwrite = *overwritePtr
+	if config.KmsKeyID, err = tools.ParseKMSKeyID(*kmsKeyIDPtr); err != nil {
+		return config, err
+	}
+	if config.ReplicaRegions, err = tools.ParseReplicaRegions(*replicaRegionsPtr); err != nil {
+		return config, err
+	}
+	config.RestoreDeleted = *restoreDeletedPtr
+	config.Plan = *planPtr
+	config.Debug = *debugPtr
+
+	if config.SourceProfile == config.DestinationProfile && config.SourceRegion == config.DestinationRegion {
+		return config, errors.New("the source and destination profile and region are the same")
+	}
+	return config, nil
+}
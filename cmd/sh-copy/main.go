@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/copier"
+	"github.com/natemarks/secret-hoard/store"
+)
+
+func main() {
+	cfg, err := GetConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	src, err := store.ForProfile(cfg.SourceProfile, cfg.SourceRegion)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load source secret store")
+	}
+	dst, err := store.ForProfile(cfg.DestinationProfile, cfg.DestinationRegion)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load destination secret store")
+	}
+	c := copier.Copier{
+		Source:            src,
+		Destination:       dst,
+		Overwrite:         cfg.Overwrite,
+		KmsKeyID:          cfg.KmsKeyID,
+		ReplicaRegions:    cfg.ReplicaRegions,
+		DestinationRegion: store.Region(dst),
+		RestoreDeleted:    cfg.RestoreDeleted,
+		Plan:              cfg.Plan,
+	}
+	results, err := c.Copy(cfg.SecretIDs, cfg.TagFilters, &log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("copy failed")
+	}
+	log.Info().Msg(results.Summary())
+	if failed := results.Failed(); failed > 0 {
+		log.Fatal().Msgf("%d of %d secrets failed", failed, len(results))
+	}
+}
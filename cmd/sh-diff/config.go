@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	FilePath       string            // the CSV, YAML or JSON file sh-upload consumes
+	Concurrency    int               // number of records compared at the same time
+	MaxAttempts    int               // attempts per Secrets Manager call. 1 disables retries
+	RetryDelay     time.Duration     // upper bound of the first retry backoff
+	ReplicaRegions []string          // replica regions of the records without a ReplicaRegions value
+	KmsKeyID       string            // KMS key of the records without a KmsKeyId value or an environment in KmsKeys
+	KmsKeys        map[string]string // environment -> KMS key of the records without a KmsKeyId value
+	Debug          bool              // enable debug mode
+}
+
+// GetLogger returns a logger for the application. It writes to stderr so the diff is the
+// only output on stdout
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stderr).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// UploadConfig returns the sh-upload configuration used to read and compare the file
+func (c Config) UploadConfig() tools.Config {
+	return tools.Config{
+		FilePath:       c.FilePath,
+		Debug:          c.Debug,
+		Plan:           true,
+		Concurrency:    c.Concurrency,
+		MaxAttempts:    c.MaxAttempts,
+		RetryDelay:     c.RetryDelay,
+		ReplicaRegions: c.ReplicaRegions,
+		KmsKeyID:       c.KmsKeyID,
+		KmsKeys:        c.KmsKeys,
+	}
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config, err error) {
+	// Define flags
+	filePtr := flag.String("file", "", "Path to the CSV, YAML or JSON file to compare with the stored secrets")
+	concurrencyPtr := flag.Int("concurrency", 1, "Number of records to compare at the same time")
+	maxAttemptsPtr := flag.Int("max-attempts", store.DefaultRetryPolicy.MaxAttempts, "Attempts per Secrets Manager call when it's throttled or fails with a transient error")
+	retryDelayPtr := flag.Duration("retry-delay", store.DefaultRetryPolicy.BaseDelay, "Upper bound of the first jittered retry backoff. It doubles after every attempt")
+	replicaRegionsPtr := flag.String("replica-regions", "", "Regions the secrets are replicated to separated by commas like sh-upload -replica-regions")
+	kmsKeyIDPtr := flag.String("kms-key-id", "", "KMS key ARN the secrets are encrypted with like sh-upload -kms-key-id")
+	kmsKeysPtr := flag.String("kms-keys", "", "YAML or JSON file of environment: KMS key ARN defaults like sh-upload -kms-keys")
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	// Parse command line arguments
+	flag.Parse()
+	config.FilePath = *filePtr
+	config.Concurrency = *concurrencyPtr
+	config.MaxAttempts = *maxAttemptsPtr
+	config.RetryDelay = *retryDelayPtr
+	config.Debug = *debugPtr
+	if config.ReplicaRegions, err = tools.ParseReplicaRegions(*replicaRegionsPtr); err != nil {
+		return config, err
+	}
+	if config.KmsKeyID, err = tools.ParseKMSKeyID(*kmsKeyIDPtr); err != nil {
+		return config, err
+	}
+	if *kmsKeysPtr != "" {
+		if config.KmsKeys, err = tools.ReadKMSKeys(*kmsKeysPtr); err != nil {
+			return config, err
+		}
+	}
+
+	if !tools.FileExists(config.FilePath) {
+		return config, fmt.Errorf("invalid file path: %s", config.FilePath)
+	}
+	if config.Concurrency < 1 {
+		return config, fmt.Errorf("invalid concurrency: %d", config.Concurrency)
+	}
+	if config.MaxAttempts < 1 {
+		return config, fmt.Errorf("invalid max attempts: %d", config.MaxAttempts)
+	}
+	return config, nil
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/uploader"
+)
+
+// exit codes for scheduled checks
+const (
+	exitDrift = 1 // a secret doesn't match the file
+	exitError = 2 // the file or a record couldn't be compared
+)
+
+func main() {
+	cfg, err := GetConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitError)
+	}
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	st, err := store.Default()
+	if err != nil {
+		log.Error().Err(err).Msg("unable to load secret store")
+		os.Exit(exitError)
+	}
+	diff, err := uploader.ManifestProcessor{Store: st}.Diff(cfg.UploadConfig(), &log)
+	if diff != nil {
+		uploader.WriteDiff(os.Stdout, diff)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("diff failed")
+		os.Exit(exitError)
+	}
+	if failed := diff.Failed(); failed > 0 {
+		log.Error().Msgf("%d records couldn't be compared", failed)
+		os.Exit(exitError)
+	}
+	if drifted := diff.Drifted(); drifted > 0 {
+		log.Error().Msgf("drift detected: %d secrets don't match %s", drifted, cfg.FilePath)
+		os.Exit(exitDrift)
+	}
+	log.Info().Msgf("no drift: %s", cfg.FilePath)
+}
@@ -13,9 +13,11 @@ import (
 
 // Config is the configuration for the application
 type Config struct {
-	SecretID string // the secret ID to download
-	FilePath string // the file path to write the secret to
-	Debug    bool   // enable debug mode
+	SecretID     string // the secret ID to download
+	FilePath     string // the file path to write the secret to
+	VersionID    string // the version ID to download. defaults to the AWSCURRENT version
+	VersionStage string // the version stage to download ex. AWSPREVIOUS, AWSPENDING
+	Debug        bool   // enable debug mode
 }
 
 // GetLogger returns a logger for the application
@@ -33,12 +35,16 @@ func GetConfig() (config Config, err error) {
 	// Define flags
 	secretIDPtr := flag.String("secret", "", "Secret ID to get")
 	filePtr := flag.String("file", "", "Path to the file")
+	versionIDPtr := flag.String("version-id", "", "Version ID to get. Defaults to the AWSCURRENT version")
+	versionStagePtr := flag.String("version-stage", "", "Version stage to get ex. AWSPREVIOUS, AWSPENDING or a custom label")
 	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
 
 	// Parse command line arguments
 	flag.Parse()
 	config.FilePath = *filePtr
 	config.SecretID = *secretIDPtr
+	config.VersionID = *versionIDPtr
+	config.VersionStage = *versionStagePtr
 	config.Debug = *debugPtr
 
 	if tools.FileExists(config.FilePath) {
@@ -4,6 +4,7 @@ import (
 	"os"
 
 	"github.com/natemarks/secret-hoard/get"
+	"github.com/natemarks/secret-hoard/store"
 )
 
 func main() {
@@ -13,9 +14,14 @@ func main() {
 	}
 	log := cfg.GetLogger()
 	log.Info().Msgf("config: %+v", cfg)
-	err = get.DownloadSecret(cfg.SecretID, cfg.FilePath, &log)
+	st, err := store.Default()
 	if err != nil {
-		log.Fatal().Err(err).Msg("DownloadSecret() error")
+		log.Fatal().Err(err).Msg("unable to load secret store")
+	}
+	version := get.Version{ID: cfg.VersionID, Stage: cfg.VersionStage}
+	err = get.DownloadSecretVersion(st, cfg.SecretID, version, cfg.FilePath, &log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("DownloadSecretVersion() error")
 		os.Exit(1)
 	}
 }
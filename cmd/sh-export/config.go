@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	Dir        string            // directory the CSV files and secret files are written to
+	TagFilters map[string]string // export the secrets whose tags match every key/value
+	Debug      bool              // enable debug mode
+}
+
+// GetLogger returns a logger for the application
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stdout).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config, err error) {
+	tags := tools.TagFlags{}
+	// Define flags
+	dirPtr := flag.String("dir", "", "Directory to write the CSV files and the secret files to. It must be empty or not exist")
+	flag.String("source", "secret-hoard", "Source tag to filter by")
+	flag.String("resource-type", "", "ResourceType tag to filter by ex. rdspostgres")
+	flag.String("environment", "", "Environment tag to filter by")
+	flag.Var(tags, "tag", "Tag key=value to filter by. Can be repeated")
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	// Parse command line arguments
+	flag.Parse()
+	if err = tags.Merge(flag.CommandLine, map[string]string{
+		"source":        "Source",
+		"resource-type": "ResourceType",
+		"environment":   "Environment",
+	}); err != nil {
+		return config, err
+	}
+	config.Dir = *dirPtr
+	config.TagFilters = tags
+	config.Debug = *debugPtr
+
+	if config.Dir == "" {
+		return config, errors.New("-dir is required")
+	}
+	if entries, err := os.ReadDir(config.Dir); err == nil && len(entries) > 0 {
+		return config, fmt.Errorf("directory isn't empty: %s", config.Dir)
+	}
+	return config, nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/export"
+	"github.com/natemarks/secret-hoard/store"
+)
+
+func main() {
+	cfg, err := GetConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	st, err := store.Default()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load secret store")
+	}
+	results, err := export.ExportSecrets(st, cfg.TagFilters, cfg.Dir, &log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("export failed")
+	}
+	log.Info().Msgf("exported %d of %d secrets to %s", len(results)-results.Failed(), len(results), cfg.Dir)
+	if failed := results.Failed(); failed > 0 {
+		log.Fatal().Msgf("%d of %d secrets failed", failed, len(results))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	TagFilters map[string]string // list the secrets whose tags match every key/value
+	Format     string            // output format: table, json or csv
+	Debug      bool              // enable debug mode
+}
+
+// GetLogger returns a logger for the application
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stderr).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config, err error) {
+	tags := tools.TagFlags{}
+	// Define flags
+	flag.String("source", "secret-hoard", "Source tag to filter by. Empty lists secrets from every source")
+	flag.String("resource-type", "", "ResourceType tag to filter by ex. rdspostgres")
+	flag.String("environment", "", "Environment tag to filter by")
+	flag.String("access", "", "Access tag to filter by")
+	flag.Var(tags, "tag", "Tag key=value to filter by. Can be repeated")
+	formatPtr := flag.String("format", tools.FormatTable, "Output format: "+strings.Join(tools.InventoryFormats, ", "))
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	// Parse command line arguments
+	flag.Parse()
+	if err = tags.Merge(flag.CommandLine, map[string]string{
+		"source":        "Source",
+		"resource-type": "ResourceType",
+		"environment":   "Environment",
+		"access":        "Access",
+	}); err != nil {
+		return config, err
+	}
+	config.TagFilters = tags
+	config.Format = *formatPtr
+	config.Debug = *debugPtr
+
+	if !slices.Contains(tools.InventoryFormats, config.Format) {
+		return config, fmt.Errorf("-format must be one of: %s", strings.Join(tools.InventoryFormats, ", "))
+	}
+	return config, nil
+}
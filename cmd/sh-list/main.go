@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+func main() {
+	cfg, err := GetConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	st, err := store.Default()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load secret store")
+	}
+	entries, err := tools.ListSecrets(st, cfg.TagFilters)
+	if err != nil {
+		log.Fatal().Err(err).Msg("error listing secrets")
+	}
+	log.Debug().Msgf("listed %d secrets", len(entries))
+	if err = tools.WriteInventory(os.Stdout, entries, cfg.Format); err != nil {
+		log.Fatal().Err(err).Msg("error writing the secrets")
+	}
+}
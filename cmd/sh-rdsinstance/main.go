@@ -1,45 +1,24 @@
 package main
 
 import (
-	"strings"
-
-	"github.com/natemarks/secret-hoard/rdspostgres"
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/uploader"
 )
 
-var secrets []rdspostgres.Secret
-
 func main() {
 	cfg, err := tools.GetConfig()
 	if err != nil {
 		panic(err)
 	}
 	log := cfg.GetLogger()
-	log.Info().Msgf("config: %+v", cfg)
-	records, err := rdspostgres.RecordsFromCSV(cfg.FilePath, &log)
+	log.Info().Object("config", cfg).Msg("config")
+	st, err := store.Default()
 	if err != nil {
-		log.Fatal().Err(err).Msgf("error reading secrets from file %s", cfg.FilePath)
-	}
-	for _, record := range records {
-		// skip header row
-		if strings.ToLower(record.ResourceType) == "resourcetype" {
-			continue
-		}
-		secret, err := rdspostgres.FromCSVRecord(record, &log)
-		if err != nil {
-			log.Error().Err(err).Msgf("error converting record to secret: %v", record)
-			continue
-		}
-		secrets = append(secrets, secret)
+		log.Fatal().Err(err).Msg("unable to load secret store")
 	}
-
-	for _, secret := range secrets {
-		if secret.Exists(&log) {
-			log.Debug().Msgf("secret already exists: %s", secret.Metadata.SecretID())
-			secret.Update(cfg.Overwrite, &log)
-			continue
-		}
-		log.Debug().Msgf("secret does not exist: %s", secret.Metadata.SecretID())
-		secret.Create(&log)
+	err = uploader.Run(uploader.RDSPostgresProcessor{Store: st}, cfg, &log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("upload failed")
 	}
 }
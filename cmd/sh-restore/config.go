@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/backup"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	File           string // archive file written by sh-backup
+	IdentityFile   string // age identity file to decrypt with
+	PassphraseFile string // file to read the passphrase from instead of SECRET_HOARD_PASSPHRASE
+	Profile        string // AWS profile to restore the secrets to. empty is the default profile
+	Region         string // AWS region to restore the secrets to. empty is the profile region
+	Overwrite      bool   // restore secrets that already exist
+	Verify         bool   // only decrypt the archive and verify the checksums
+	Debug          bool   // enable debug mode
+}
+
+// GetLogger returns a logger for the application
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stdout).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config, err error) {
+	// Define flags
+	filePtr := flag.String("file", "", "Archive file written by sh-backup")
+	identityPtr := flag.String("identity", "", "age identity file to decrypt with. Without it the archive is decrypted with the passphrase")
+	passphraseFilePtr := flag.String("passphrase-file", "", "File to read the passphrase from. Defaults to the "+backup.PassphraseEnv+" environment variable")
+	profilePtr := flag.String("profile", "", "AWS profile to restore the secrets to. Defaults to the default profile")
+	regionPtr := flag.String("region", "", "AWS region to restore the secrets to. Defaults to the region of the profile")
+	overwritePtr := flag.Bool("overwrite", false, "Restore secrets that already exist as new versions")
+	verifyPtr := flag.Bool("verify", false, "Only decrypt the archive and verify the checksums")
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	// Parse command line arguments
+	flag.Parse()
+	config.File = *filePtr
+	config.IdentityFile = *identityPtr
+	config.PassphraseFile = *passphraseFilePtr
+	config.Profile = *profilePtr
+	config.Region = *regionPtr
+	config.Overwrite = *overwritePtr
+	config.Verify = *verifyPtr
+	config.Debug = *debugPtr
+
+	if config.File == "" {
+		return config, errors.New("-file is required")
+	}
+	if _, err := os.Stat(config.File); err != nil {
+		return config, fmt.Errorf("file not found: %s", config.File)
+	}
+	if config.IdentityFile != "" && config.PassphraseFile != "" {
+		return config, errors.New("-identity and -passphrase-file can't be used together")
+	}
+	return config, nil
+}
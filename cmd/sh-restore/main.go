@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/backup"
+	"github.com/natemarks/secret-hoard/store"
+)
+
+func main() {
+	cfg, err := GetConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	err = backup.VerifyChecksumFile(cfg.File)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		log.Warn().Msgf("no checksum file, skipping the archive checksum: %s", backup.ChecksumPath(cfg.File))
+	case err != nil:
+		log.Fatal().Err(err).Msg("archive verification failed")
+	}
+	passphrase, err := backup.ReadPassphrase(cfg.PassphraseFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to read passphrase")
+	}
+	identities, err := backup.Identities(cfg.IdentityFile, passphrase)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load identities")
+	}
+	file, err := os.Open(cfg.File)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to open archive")
+	}
+	archive, err := backup.Read(file, identities...)
+	_ = file.Close()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to read archive")
+	}
+	log.Info().Msgf("archive created %s with %d secrets", archive.CreatedDate.Format("2006-01-02T15:04:05Z"), len(archive.Secrets))
+	if cfg.Verify {
+		return
+	}
+	st, err := store.ForProfile(cfg.Profile, cfg.Region)
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load secret store")
+	}
+	results, err := backup.Restore(st, archive, cfg.Overwrite, &log)
+	log.Info().Msgf("restored %d secrets: %d created, %d updated, %d skipped, %d failed",
+		len(results), results.Count(store.ActionCreate), results.Count(store.ActionUpdate), results.Count(store.ActionSkip), results.Failed())
+	if err != nil {
+		log.Fatal().Err(err).Msg("restore failed")
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	SecretID  string // the secret ID to roll back
+	VersionID string // the version to move the stage to. empty to swap AWSCURRENT and AWSPREVIOUS
+	Stage     string // the version stage to move
+	List      bool   // only list the versions
+	Debug     bool   // enable debug mode
+}
+
+// GetLogger returns a logger for the application
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stderr).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config, err error) {
+	// Define flags
+	secretIDPtr := flag.String("secret", "", "Secret ID to roll back")
+	versionIDPtr := flag.String("version-id", "", "Version ID to move the stage to. Without it AWSCURRENT and AWSPREVIOUS are swapped")
+	stagePtr := flag.String("stage", store.StageCurrent, "Version stage to move to -version-id")
+	listPtr := flag.Bool("list", false, "List the versions and their stages without changing anything")
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	// Parse command line arguments
+	flag.Parse()
+	config.SecretID = *secretIDPtr
+	config.VersionID = *versionIDPtr
+	config.Stage = *stagePtr
+	config.List = *listPtr
+	config.Debug = *debugPtr
+
+	if config.SecretID == "" {
+		return config, errors.New("-secret is required")
+	}
+	if config.VersionID == "" && config.Stage != store.StageCurrent {
+		return config, errors.New("-version-id is required to move a stage other than AWSCURRENT")
+	}
+	return config, nil
+}
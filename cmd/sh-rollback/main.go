@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+func main() {
+	cfg, err := GetConfig()
+	if err != nil {
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	st, err := store.Default()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load secret store")
+	}
+
+	if cfg.List {
+		versions, err := tools.GetSecretVersions(st, cfg.SecretID)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("error listing versions: %s", cfg.SecretID)
+		}
+		tools.WriteVersions(os.Stdout, versions)
+		return
+	}
+
+	var before, after []store.Version
+	if cfg.VersionID == "" {
+		log.Info().Msgf("swapping %s and %s: %s", store.StageCurrent, store.StagePrevious, cfg.SecretID)
+		before, after, err = tools.RollbackSecret(st, cfg.SecretID)
+	} else {
+		log.Info().Msgf("moving %s to %s: %s", cfg.Stage, cfg.VersionID, cfg.SecretID)
+		before, after, err = tools.MoveVersionStage(st, cfg.SecretID, cfg.Stage, cfg.VersionID)
+	}
+	if before != nil {
+		fmt.Println("before:")
+		tools.WriteVersions(os.Stdout, before)
+	}
+	if err != nil {
+		log.Fatal().Err(err).Msgf("error moving version stage: %s", cfg.SecretID)
+	}
+	fmt.Println("after:")
+	tools.WriteVersions(os.Stdout, after)
+}
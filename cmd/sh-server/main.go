@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"os"
+
+	"github.com/natemarks/secret-hoard/smserver"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/version"
+	"github.com/rs/zerolog"
+)
+
+// Config is the configuration for the application
+type Config struct {
+	Addr     string // listen address
+	FilePath string // JSON file store. secrets are kept in memory when empty
+	Debug    bool   // enable debug mode
+}
+
+// GetLogger returns a logger for the application
+func (c Config) GetLogger() (log zerolog.Logger) {
+	log = zerolog.New(os.Stdout).With().Str("version", version.Version).Timestamp().Logger()
+	log = log.Level(zerolog.InfoLevel)
+	if c.Debug {
+		log = log.Level(zerolog.DebugLevel)
+	}
+	return log
+}
+
+// GetConfig returns the configuration for the application
+func GetConfig() (config Config) {
+	addrPtr := flag.String("addr", "127.0.0.1:4566", "Listen address")
+	filePtr := flag.String("file", "", "Path to the JSON file store. Secrets are kept in memory if empty")
+	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+
+	flag.Parse()
+	config.Addr = *addrPtr
+	config.FilePath = *filePtr
+	config.Debug = *debugPtr
+	return config
+}
+
+func main() {
+	cfg := GetConfig()
+	log := cfg.GetLogger()
+	log.Info().Msgf("config: %+v", cfg)
+	var st store.SecretStore = store.NewMemoryStore()
+	if cfg.FilePath != "" {
+		st = store.NewFileStore(cfg.FilePath)
+	}
+	server := smserver.New(st)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.Debug().Msgf("%s %s", r.Method, r.Header.Get("X-Amz-Target"))
+		server.ServeHTTP(w, r)
+	})
+	log.Info().Msgf("listening on http://%s", cfg.Addr)
+	err := http.ListenAndServe(cfg.Addr, handler)
+	if err != nil {
+		log.Fatal().Err(err).Msg("server error")
+	}
+}
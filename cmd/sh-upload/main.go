@@ -1,38 +1,36 @@
 package main
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
 	"github.com/natemarks/secret-hoard/uploader"
-	"github.com/rs/zerolog"
 )
 
-// GetCSVProcessor returns the appropriate CSVProcessor based on the config
-func GetCSVProcessor(cfg tools.Config, log *zerolog.Logger) uploader.CSVProcessor {
-	switch csvType := cfg.CSVType(); csvType {
-	case "rdspostgres":
-		return uploader.RDSPostgresProcessor{}
-	case "snowflake":
-		return uploader.SnowflakeProcessor{}
-	case "text_file":
-		return uploader.TextFileProcessor{}
-	case "jsondoc":
-		return uploader.JSONDocProcessor{}
-	case "ssl_certificate":
-		return uploader.SSLCertProcessor{}
-	default:
-		log.Fatal().Msgf("unknown CSV type: %s", csvType)
-		return nil
-
-	}
+// confirmPrune asks on stdin whether the secrets -prune would delete should be deleted
+func confirmPrune(secretIDs []string) bool {
+	fmt.Printf("Schedule the deletion of %d secrets? Only 'yes' will be accepted: ", len(secretIDs))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimSpace(answer) == "yes"
 }
+
 func main() {
-	cfg, err := tools.GetConfig()
+	cfg, err := tools.GetUploadConfig()
 	if err != nil {
 		panic(err)
 	}
 	log := cfg.GetLogger()
-	log.Info().Msgf("config: %+v", cfg)
-	processor := GetCSVProcessor(cfg, &log)
-	processor.Process(cfg, &log)
-
+	log.Info().Object("config", cfg).Msg("config")
+	st, err := store.Default()
+	if err != nil {
+		log.Fatal().Err(err).Msg("unable to load secret store")
+	}
+	err = uploader.Run(uploader.ManifestProcessor{Store: st, Confirm: confirmPrune}, cfg, &log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("upload failed")
+	}
 }
@@ -0,0 +1,152 @@
+// Package copier copies secrets with their tags from one secret store to another ex. from
+// one AWS account or region to another
+package copier
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/uploader"
+	"github.com/natemarks/secret-hoard/writer"
+	"github.com/rs/zerolog"
+)
+
+// Result is the outcome of copying one secret
+type Result struct {
+	SecretID  string
+	Action    store.Action // create, update, unchanged or skip. empty when Err is set
+	VersionID string       // resulting AWSCURRENT version in the destination. empty in plan mode
+	Err       error
+}
+
+// Results are the outcomes of a copy
+type Results []Result
+
+// Count returns the number of results with the action
+func (r Results) Count(action store.Action) (count int) {
+	for _, result := range r {
+		if result.Err == nil && result.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of secrets that couldn't be copied
+func (r Results) Failed() (count int) {
+	for _, result := range r {
+		if result.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Summary returns the number of secrets by outcome
+func (r Results) Summary() string {
+	return fmt.Sprintf("created: %d, updated: %d, unchanged: %d, skipped: %d, failed: %d",
+		r.Count(store.ActionCreate), r.Count(store.ActionUpdate), r.Count(store.ActionUnchanged),
+		r.Count(store.ActionSkip), r.Failed())
+}
+
+// Copier copies secrets from Source to Destination. Transient errors of both stores are
+// retried with store.DefaultRetryPolicy
+type Copier struct {
+	Source         store.SecretStore
+	Destination    store.SecretStore
+	Overwrite      bool     // update destination secrets whose value, tags, replicas or KMS key differ
+	KmsKeyID       string   // KMS key of the destination secrets. the key isn't managed when empty
+	ReplicaRegions []string // replica regions of the destination secrets. defaults to the replicas of the source secret
+	// DestinationRegion is the region of Destination. It's left out of the replica regions
+	// copied from a source secret because a secret can't have a replica in its own region
+	DestinationRegion string
+	RestoreDeleted    bool // restore destination secrets scheduled for deletion before updating them
+	Plan              bool // print what would change without writing anything
+}
+
+// sourceSecrets returns the descriptions of the secret IDs or, when there are none, of the
+// secrets whose tags match every key/value in tagFilters sorted by name
+func sourceSecrets(ctx context.Context, src store.SecretStore, secretIDs []string, tagFilters map[string]string) ([]store.Description, error) {
+	if len(secretIDs) == 0 {
+		descriptions, err := src.List(ctx, tagFilters)
+		if err != nil {
+			return nil, fmt.Errorf("error listing source secrets: %w", err)
+		}
+		sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+		return descriptions, nil
+	}
+	descriptions := make([]store.Description, len(secretIDs))
+	for i, secretID := range secretIDs {
+		description, err := src.Describe(ctx, secretID)
+		if err != nil {
+			return nil, fmt.Errorf("error describing source secret %s: %w", secretID, err)
+		}
+		descriptions[i] = description
+	}
+	return descriptions, nil
+}
+
+// Copy copies the value and tags of the secret IDs or, when there are none, of the secrets
+// whose tags match every key/value in tagFilters. The copies are written like sh-upload
+// writes records: missing secrets are created and existing secrets are only updated when
+// their value, tags, replica regions or KMS key differ and Overwrite is true. Tags that are
+// only on the destination secret are kept. With Plan the changes are printed instead. The
+// error is only set when the source secrets can't be listed or described. Failed secrets are
+// reported in the Results
+func (c Copier) Copy(secretIDs []string, tagFilters map[string]string, log *zerolog.Logger) (Results, error) {
+	ctx := context.Background()
+	src := store.NewRetryStore(c.Source, store.DefaultRetryPolicy)
+	dst := store.NewRetryStore(c.Destination, store.DefaultRetryPolicy)
+	descriptions, err := sourceSecrets(ctx, src, secretIDs, tagFilters)
+	if err != nil {
+		return nil, err
+	}
+	processed := make(uploader.Results, len(descriptions))
+	secrets := make([]writer.Secret, len(descriptions))
+	for i, description := range descriptions {
+		processed[i].SecretID = description.Name
+		if secrets[i], processed[i].Err = c.copySecret(ctx, src, dst, description); processed[i].Err != nil {
+			log.Error().Err(processed[i].Err).Msgf("error copying secret: %s", description.Name)
+		}
+	}
+	cfg := tools.Config{Overwrite: c.Overwrite, Plan: c.Plan, RestoreDeleted: c.RestoreDeleted}
+	processed = uploader.ProcessSecrets(cfg, processed, secrets, log)
+
+	results := make(Results, len(processed))
+	for i, result := range processed {
+		results[i] = Result{SecretID: result.SecretID, Action: result.Action, VersionID: result.VersionID, Err: result.Err}
+		if result.Err == nil && !c.Plan {
+			log.Info().Msgf("copied secret (%s): %s", result.Action, result.SecretID)
+		}
+	}
+	return results, nil
+}
+
+// copySecret returns the secret that copies the current value, the tags and the replica
+// regions of a source secret to dst
+func (c Copier) copySecret(ctx context.Context, src, dst store.SecretStore, description store.Description) (secret writer.Secret, err error) {
+	value, err := src.Get(ctx, description.Name)
+	if err != nil {
+		return secret, fmt.Errorf("error reading source secret: %w", err)
+	}
+	replicaRegions := c.ReplicaRegions
+	if len(replicaRegions) == 0 {
+		// List doesn't return the replicas
+		if description, err = src.Describe(ctx, description.Name); err != nil {
+			return secret, fmt.Errorf("error describing source secret: %w", err)
+		}
+		replicaRegions = slices.DeleteFunc(description.ReplicaRegions(), func(region string) bool {
+			return region == c.DestinationRegion
+		})
+	}
+	return writer.Secret{
+		Name:     description.Name,
+		Value:    value,
+		Metadata: description.Tags,
+		Options:  store.Options{ReplicaRegions: replicaRegions, KmsKeyID: c.KmsKeyID, Store: dst},
+	}, nil
+}
@@ -0,0 +1,103 @@
+package copier
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	log := tools.TestLogger()
+	src := store.NewMemoryStore()
+	dst := store.NewMemoryStore()
+	managed := map[string]string{"Source": "secret-hoard", "Environment": "testenv"}
+	for secretID, value := range map[string]string{
+		"text_file/testenv/a": `{"contents":"a"}`,
+		"text_file/testenv/b": `{"contents":"b"}`,
+		"text_file/testenv/c": `{"contents":"c"}`,
+	} {
+		if _, err := src.Create(ctx, secretID, value, managed, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := src.Create(ctx, "other/testenv/d", "d", map[string]string{"Source": "other"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	// b is stale in the destination and c already matches
+	if _, err := dst.Create(ctx, "text_file/testenv/b", `{"contents":"old"}`, map[string]string{"Owner": "me"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dst.Create(ctx, "text_file/testenv/c", `{"contents":"c"}`, managed, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := src.ReplicateRegions(ctx, "text_file/testenv/a", []string{"us-west-2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := Copier{Source: src, Destination: dst, Plan: true}
+	results, err := c.Copy(nil, map[string]string{"Source": "secret-hoard"}, &log)
+	if err != nil || len(results) != 3 || results.Count(store.ActionCreate) != 1 || results.Count(store.ActionSkip) != 1 {
+		t.Fatalf("Copy(plan) = %+v, %v", results, err)
+	}
+	if exists, _ := store.Exists(ctx, dst, "text_file/testenv/a"); exists {
+		t.Fatal("Copy(plan) created a secret")
+	}
+
+	c.Plan = false
+	results, err = c.Copy(nil, map[string]string{"Source": "secret-hoard"}, &log)
+	if err != nil || results.Summary() != "created: 1, updated: 0, unchanged: 1, skipped: 1, failed: 0" {
+		t.Fatalf("Copy() = %+v, %v", results, err)
+	}
+	description, err := dst.Describe(ctx, "text_file/testenv/a")
+	if err != nil || len(description.ReplicaRegions()) != 1 || description.ReplicaRegions()[0] != "us-west-2" {
+		t.Errorf("copied replicas = %v, %v, want the source replicas", description.ReplicaRegions(), err)
+	}
+	c.Overwrite = true
+	results, err = c.Copy([]string{"text_file/testenv/b"}, nil, &log)
+	if err != nil || len(results) != 1 || results[0].Action != store.ActionUpdate {
+		t.Fatalf("Copy(overwrite) = %+v, %v", results, err)
+	}
+	value, err := dst.Get(ctx, "text_file/testenv/b")
+	if err != nil || value != `{"contents":"b"}` {
+		t.Errorf("Get() = %q, %v", value, err)
+	}
+	description, err = dst.Describe(ctx, "text_file/testenv/b")
+	if err != nil || description.Tags["Source"] != "secret-hoard" || description.Tags["Owner"] != "me" {
+		t.Errorf("copied tags = %v, %v, want the source tags and Owner", description.Tags, err)
+	}
+
+	if _, err = c.Copy([]string{"text_file/testenv/missing"}, nil, &log); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Copy(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCopyAcrossRegions(t *testing.T) {
+	ctx := context.Background()
+	log := tools.TestLogger()
+	src := store.NewMemoryStore()
+	dst := store.NewMemoryStore()
+	secretID := "text_file/testenv/a"
+	if _, err := src.Create(ctx, secretID, `{"contents":"a"}`, map[string]string{"Source": "secret-hoard"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.ReplicateRegions(ctx, secretID, []string{"us-west-2", "eu-west-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the source replica in the destination region is left out
+	c := Copier{Source: src, Destination: dst, DestinationRegion: "us-west-2"}
+	results, err := c.Copy([]string{secretID}, nil, &log)
+	if err != nil || results.Failed() != 0 {
+		t.Fatalf("Copy() = %+v, %v", results, err)
+	}
+	description, err := dst.Describe(ctx, secretID)
+	if err != nil || !reflect.DeepEqual(description.ReplicaRegions(), []string{"eu-west-1"}) {
+		t.Errorf("copied replicas = %v, %v, want [eu-west-1]", description.ReplicaRegions(), err)
+	}
+}
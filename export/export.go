@@ -0,0 +1,149 @@
+// Package export writes stored secrets back to the CSV files sh-upload consumes
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/natemarks/secret-hoard/resources"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/rs/zerolog"
+)
+
+// FilesDir is the directory in the export directory that the secret files are written to
+const FilesDir = "files"
+
+// Result is the outcome of exporting one secret
+type Result struct {
+	SecretID     string
+	ResourceType string // ResourceType tag of the secret
+	File         string // CSV file the secret was written to. empty when Err is set
+	Err          error
+}
+
+// Results are the outcomes of an export
+type Results []Result
+
+// Failed returns the number of secrets that couldn't be exported
+func (r Results) Failed() (count int) {
+	for _, result := range r {
+		if result.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// CSVPath returns the path of the CSV file of a resource type in the export directory
+func CSVPath(dir, resourceType string) string {
+	return filepath.Join(dir, resourceType+".csv")
+}
+
+// ExportSecrets writes the secrets whose tags match every key/value in tagFilters to a CSV
+// file per resource type in dir with the columns of the resource type. The files of sslcert,
+// jsondoc and text_file secrets are written to dir/files and the CSV rows point at them.
+// Transient errors are retried with store.DefaultRetryPolicy. The error is only set when the
+// secrets can't be listed or a CSV file can't be written
+func ExportSecrets(st store.SecretStore, tagFilters map[string]string, dir string, log *zerolog.Logger) (Results, error) {
+	ctx := context.Background()
+	retryStore := store.NewRetryStore(st, store.DefaultRetryPolicy)
+	descriptions, err := retryStore.List(ctx, tagFilters)
+	if err != nil {
+		return nil, fmt.Errorf("error listing secrets: %w", err)
+	}
+	sort.Slice(descriptions, func(i, j int) bool { return descriptions[i].Name < descriptions[j].Name })
+	filesDir := filepath.Join(dir, FilesDir)
+	if err = os.MkdirAll(filesDir, 0o700); err != nil {
+		return nil, err
+	}
+
+	var results Results
+	rows := map[string][][]string{}
+	for _, description := range descriptions {
+		result := Result{SecretID: description.Name, ResourceType: description.Tags["ResourceType"]}
+		resource, ok := resources.Lookup(result.ResourceType)
+		if !ok {
+			result.Err = fmt.Errorf("unsupported ResourceType tag %q: %s", result.ResourceType, description.Name)
+			log.Error().Err(result.Err).Msg("error exporting secret")
+			results = append(results, result)
+			continue
+		}
+		log.Debug().Msgf("exporting secret: %s", description.Name)
+		// List doesn't return the replicas
+		described, err := retryStore.Describe(ctx, description.Name)
+		var value string
+		if err == nil {
+			value, err = retryStore.Get(ctx, description.Name)
+		}
+		if err == nil {
+			var values []string
+			if values, err = resource.CSVValues(described, value, filesDir); err == nil {
+				rows[result.ResourceType] = append(rows[result.ResourceType], values)
+				result.File = CSVPath(dir, result.ResourceType)
+			}
+		}
+		if err != nil {
+			result.Err = fmt.Errorf("%s: %w", description.Name, err)
+			log.Error().Err(result.Err).Msg("error exporting secret")
+		}
+		results = append(results, result)
+	}
+
+	for name, resourceRows := range rows {
+		resource, _ := resources.Lookup(name)
+		if err = writeCSV(CSVPath(dir, name), resource.Columns, resourceRows); err != nil {
+			return results, err
+		}
+		log.Info().Msgf("exported %d secrets: %s", len(resourceRows), CSVPath(dir, name))
+	}
+	return results, nil
+}
+
+// writeCSV writes a CSV file with a header of the column names. Optional columns that are
+// empty in every row are left out
+func writeCSV(path string, columns []tools.CSVColumn, rows [][]string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	var keep []int
+	for i, column := range columns {
+		if !column.Optional || !emptyColumn(rows, i) {
+			keep = append(keep, i)
+		}
+	}
+	writer := csv.NewWriter(file)
+	header := make([]string, len(keep))
+	for i, column := range keep {
+		header[i] = columns[column].Name
+	}
+	_ = writer.Write(header)
+	for _, row := range rows {
+		values := make([]string, len(keep))
+		for i, column := range keep {
+			values[i] = row[column]
+		}
+		_ = writer.Write(values)
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// emptyColumn returns true if the column is empty in every row
+func emptyColumn(rows [][]string, column int) bool {
+	for _, row := range rows {
+		if row[column] != "" {
+			return false
+		}
+	}
+	return true
+}
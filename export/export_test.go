@@ -0,0 +1,105 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/rdspostgres"
+	"github.com/natemarks/secret-hoard/resources"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/uploader"
+)
+
+// uploadExamples uploads the example manifest to a memory store
+func uploadExamples(t *testing.T) *store.MemoryStore {
+	t.Helper()
+	content, err := os.ReadFile("../examples/manifest_example.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "manifest.csv")
+	manifest := strings.ReplaceAll(string(content), "examples/", "../examples/")
+	if err = os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	results, err := uploader.ManifestProcessor{Store: st}.Process(tools.Config{FilePath: path}, &log)
+	if err != nil || results.Failed() != 0 {
+		t.Fatalf("Process() = %v, %v", results, err)
+	}
+	return st
+}
+
+func TestExportSecrets(t *testing.T) {
+	log := tools.TestLogger()
+	st := uploadExamples(t)
+	unsupported := map[string]string{"Source": "secret-hoard", "ResourceType": "redis", "Environment": "testenv"}
+	if _, err := st.Create(context.Background(), "redis/testenv/cache", "{}", unsupported, ""); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	results, err := ExportSecrets(st, map[string]string{"Source": "secret-hoard", "Environment": "testenv"}, dir, &log)
+	if err != nil || len(results) != 6 || results.Failed() != 1 {
+		t.Fatalf("ExportSecrets() = %+v, %v, want 5 exported and 1 failed", results, err)
+	}
+	for _, name := range resources.Names() {
+		path := CSVPath(dir, name)
+		header, rows, err := tools.ReadCSVFile(path)
+		if err != nil || len(rows) != 1 {
+			t.Fatalf("ReadCSVFile(%s) = %v, %v, %v", path, header, rows, err)
+		}
+		// the exported file uploads to the same secrets
+		cfg := tools.Config{FilePath: path}
+		diff, err := uploader.ManifestProcessor{Store: st}.Diff(cfg, &log)
+		if err != nil || diff.Failed() != 0 || diff[0].Drifted() {
+			t.Errorf("Diff(%s) = %+v, %v, want in sync", path, diff, err)
+		}
+	}
+	for _, file := range []string{
+		"ssl_certificate_testenv_my.domain.com.crt",
+		"ssl_certificate_testenv_my.domain.com.key",
+		"jsondoc_testenv_some_json_access_type.json",
+		"text_file_testenv_my_file_type",
+	} {
+		info, err := os.Stat(filepath.Join(dir, FilesDir, file))
+		if err != nil {
+			t.Errorf("missing exported file: %s", file)
+		} else if info.Mode().Perm() != 0o600 {
+			t.Errorf("exported file %s mode = %v, want 0600", file, info.Mode().Perm())
+		}
+	}
+	content, err := os.ReadFile(CSVPath(dir, "rdspostgres"))
+	if err != nil || !strings.HasPrefix(string(content), strings.SplitN(rdspostgres.Record{}.CSVColumns(), "\n", 2)[0]+"\n") {
+		t.Errorf("rdspostgres.csv = %s, %v, want the CSVColumns header", content, err)
+	}
+}
+
+// describeErrorStore fails every Describe
+type describeErrorStore struct {
+	*store.MemoryStore
+}
+
+// Describe returns an access denied error
+func (d describeErrorStore) Describe(context.Context, string) (store.Description, error) {
+	return store.Description{}, errors.New("AccessDeniedException")
+}
+
+func TestExportSecretsDescribeError(t *testing.T) {
+	log := tools.TestLogger()
+	st := describeErrorStore{uploadExamples(t)}
+	filters := map[string]string{"Source": "secret-hoard", "ResourceType": "rdspostgres"}
+	results, err := ExportSecrets(st, filters, t.TempDir(), &log)
+	if err != nil || len(results) != 1 || results.Failed() != 1 {
+		t.Fatalf("ExportSecrets() = %+v, %v, want 1 failed", results, err)
+	}
+	if !strings.HasPrefix(results[0].Err.Error(), results[0].SecretID+": ") || results[0].SecretID == "" {
+		t.Errorf("ExportSecrets() error = %v, want it to name %s", results[0].Err, results[0].SecretID)
+	}
+}
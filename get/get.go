@@ -9,44 +9,77 @@ import (
 	"github.com/natemarks/secret-hoard/jsondoc"
 
 	"github.com/natemarks/secret-hoard/sslcert"
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
 	"github.com/rs/zerolog"
 )
 
-// DownloadSecret returns a secret from the secret store
+// Version selects the version of a secret to download by version ID or stage. The zero value
+// is the AWSCURRENT version
+type Version struct {
+	ID    string // version ID
+	Stage string // version stage ex. AWSPREVIOUS, AWSPENDING or a custom label
+}
+
+// String describes the version for log messages
+func (v Version) String() string {
+	switch {
+	case v.ID != "" && v.Stage != "":
+		return fmt.Sprintf("version %s (%s)", v.ID, v.Stage)
+	case v.ID != "":
+		return "version " + v.ID
+	case v.Stage != "":
+		return v.Stage
+	}
+	return store.StageCurrent
+}
+
+// DownloadSecret downloads the AWSCURRENT version of a secret from the secret store
+// st is the secret store to read from. store.Default() is used when it's nil
+func DownloadSecret(st store.SecretStore, secretID, filePath string, log *zerolog.Logger) (err error) {
+	return DownloadSecretVersion(st, secretID, Version{}, filePath, log)
+}
+
+// DownloadSecretVersion downloads a version of a secret from the secret store
+// st is the secret store to read from. store.Default() is used when it's nil
 // The execution varies depending on the resources type:
 // rdspostgres: Download the data required for a connection string to json file
 // snowflake: Download the data required for a connection string to json file
 // jsondoc: Download the json file
 // ssl_certificate: Download the certificate and private key files to filePath.crt  and filePath.key files
-func DownloadSecret(secretID, filePath string, log *zerolog.Logger) (err error) {
+func DownloadSecretVersion(st store.SecretStore, secretID string, version Version, filePath string, log *zerolog.Logger) (err error) {
 	resourceType, err := tools.GetResourceTypeFromSecretID(secretID)
 	if err != nil {
 		return err
 	}
+	st, err = store.OrDefault(st)
+	if err != nil {
+		return err
+	}
 	// use switch to handle different resource types
 	switch resourceType {
 	case "rdspostgres":
-		return DownloadValue(secretID, filePath, log)
+		return DownloadValue(st, secretID, version, filePath, log)
 	case "snowflake":
-		return DownloadValue(secretID, filePath, log)
+		return DownloadValue(st, secretID, version, filePath, log)
 	case "jsondoc":
-		return DownloadJSONContents(secretID, filePath, log)
+		return DownloadJSONContents(st, secretID, version, filePath, log)
 	case "ssl_certificate":
-		return DownloadCertAndKeyFiles(secretID, filePath, log)
+		return DownloadCertAndKeyFiles(st, secretID, version, filePath, log)
 	case "text_file":
-		return DownloadTextContents(secretID, filePath, log)
+		return DownloadTextContents(st, secretID, version, filePath, log)
 	default:
 		return fmt.Errorf("resource type not supported: %s", resourceType)
 	}
 }
 
 // DownloadValue download the secret value to a JSON file
-func DownloadValue(secretID string, filePath string, log *zerolog.Logger) (err error) {
-	log.Info().Msgf("getting secret value: %s", secretID)
-	secretValue, err := tools.GetSecretValue(secretID)
+func DownloadValue(st store.SecretStore, secretID string, version Version, filePath string, log *zerolog.Logger) (err error) {
+	log.Info().Msgf("getting secret value: %s %s", secretID, version)
+	secretValue, err := tools.GetSecretVersionValue(st, secretID, version.ID, version.Stage)
 	if err != nil {
-		log.Error().Err(err).Msgf("error getting secret value: %sd", secretID)
+		log.Error().Err(err).Msgf("error getting secret value: %s", secretID)
+		return err
 	}
 	log.Debug().Msgf("got secret value: %s", secretID)
 	// do not unmarshall the value. download the whole JSON value to the file contents for rdspostgres
@@ -61,12 +94,13 @@ func DownloadValue(secretID string, filePath string, log *zerolog.Logger) (err e
 }
 
 // DownloadJSONContents download Data.JSONContents to a file and use Data.JSONSha256Sum to verify integrity
-func DownloadJSONContents(secretID string, filePath string, log *zerolog.Logger) (err error) {
+func DownloadJSONContents(st store.SecretStore, secretID string, version Version, filePath string, log *zerolog.Logger) (err error) {
 	var result jsondoc.Data
-	log.Info().Msgf("getting secret value: %s", secretID)
-	secretValue, err := tools.GetSecretValue(secretID)
+	log.Info().Msgf("getting secret value: %s %s", secretID, version)
+	secretValue, err := tools.GetSecretVersionValue(st, secretID, version.ID, version.Stage)
 	if err != nil {
-		log.Error().Err(err).Msgf("error getting secret value: %sd", secretID)
+		log.Error().Err(err).Msgf("error getting secret value: %s", secretID)
+		return err
 	}
 	log.Debug().Msgf("got secret value: %s", secretID)
 
@@ -96,12 +130,13 @@ func DownloadJSONContents(secretID string, filePath string, log *zerolog.Logger)
 }
 
 // DownloadTextContents download Data.Contents to a file and use Data.Sha256Sum to verify integrity
-func DownloadTextContents(secretID string, filePath string, log *zerolog.Logger) (err error) {
+func DownloadTextContents(st store.SecretStore, secretID string, version Version, filePath string, log *zerolog.Logger) (err error) {
 	var result textfile.Data
-	log.Info().Msgf("getting secret value: %s", secretID)
-	secretValue, err := tools.GetSecretValue(secretID)
+	log.Info().Msgf("getting secret value: %s %s", secretID, version)
+	secretValue, err := tools.GetSecretVersionValue(st, secretID, version.ID, version.Stage)
 	if err != nil {
-		log.Error().Err(err).Msgf("error getting secret value: %sd", secretID)
+		log.Error().Err(err).Msgf("error getting secret value: %s", secretID)
+		return err
 	}
 	log.Debug().Msgf("got secret value: %s", secretID)
 
@@ -131,12 +166,13 @@ func DownloadTextContents(secretID string, filePath string, log *zerolog.Logger)
 }
 
 // DownloadCertAndKeyFiles download the certificate and private key files to filePath.crt  and filePath.key files
-func DownloadCertAndKeyFiles(secretID string, filePath string, log *zerolog.Logger) (err error) {
+func DownloadCertAndKeyFiles(st store.SecretStore, secretID string, version Version, filePath string, log *zerolog.Logger) (err error) {
 	var result sslcert.Data
-	log.Info().Msgf("getting snowflake secret value: %s", secretID)
-	secretValue, err := tools.GetSecretValue(secretID)
+	log.Info().Msgf("getting secret value: %s %s", secretID, version)
+	secretValue, err := tools.GetSecretVersionValue(st, secretID, version.ID, version.Stage)
 	if err != nil {
-		log.Error().Err(err).Msgf("error getting secret value: %sd", secretID)
+		log.Error().Err(err).Msgf("error getting secret value: %s", secretID)
+		return err
 	}
 	log.Debug().Msgf("got secret value: %s", secretID)
 
@@ -1,11 +1,12 @@
 package get
 
 import (
+	"context"
 	"testing"
-	"time"
 
 	"github.com/natemarks/secret-hoard/rdspostgres"
 
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
 )
 
@@ -29,21 +30,64 @@ func TestDBInstanceSecret(t *testing.T) {
 	if err != nil {
 		t.Errorf("FromCSVRecord() error = %v", err)
 	}
-	if secret.Exists(&log) {
-		tools.DeleteSecrets([]string{secret.Metadata.SecretID()})
-		t.Logf("waiting 30 seconds for secret deletion: %s", secret.Metadata.SecretID())
-		time.Sleep(30 * time.Second)
+	secret.Store = store.NewMemoryStore()
+	if exists, err := secret.Exists(&log); exists || err != nil {
+		t.Fatalf("Exists() = %v, %v in an empty store: %s", exists, err, secret.Metadata.SecretID())
 	}
 	t.Logf("creating secret: %s", secret.Metadata.SecretID())
-	secret.Create(&log)
+	if _, err = secret.Create(&log); err != nil {
+		t.Errorf("Create() error = %v", err)
+	}
 	t.Logf("updating secret - overwrite FALSE: %s", secret.Metadata.SecretID())
-	secret.Update(false, &log)
+	if action, _, err := secret.Update(false, &log); err != nil || action != store.ActionUnchanged {
+		t.Errorf("Update(false) = %v, %v, want unchanged", action, err)
+	}
 	t.Logf("updating secret - overwrite TRUE: %s", secret.Metadata.SecretID())
-	secret.Update(true, &log)
+	if action, _, err := secret.Update(true, &log); err != nil || action != store.ActionUnchanged {
+		t.Errorf("Update(true) = %v, %v, want unchanged", action, err)
+	}
 	t.Logf("downloading secret  (%s) to %s", secret.Metadata.SecretID(), downloadFile)
-	err = DownloadSecret(secret.Metadata.SecretID(), downloadFile, &log)
+	err = DownloadSecret(secret.Store, secret.Metadata.SecretID(), downloadFile, &log)
 	if err != nil {
 		t.Errorf("DownloadSecret() error = %v", err)
 	}
-	tools.DeleteSecrets([]string{secret.Metadata.SecretID()})
+	tools.DeleteSecrets(secret.Store, []string{secret.Metadata.SecretID()})
+	if exists, err := secret.Exists(&log); exists || err != nil {
+		t.Errorf("Exists() = %v, %v after delete: %s", exists, err, secret.Metadata.SecretID())
+	}
+}
+
+func TestDBInstanceSecretUnchangedUpdate(t *testing.T) {
+	log := tools.TestLogger()
+	secret, err := rdspostgres.FromCSVRecord(rdspostgres.Record{
+		ResourceType: "rdspostgres",
+		Environment:  "testenv",
+		Instance:     "myinstance",
+		Database:     "mydb",
+		Access:       "mytype",
+		Password:     "password",
+		Port:         5432,
+	}, &log)
+	if err != nil {
+		t.Fatalf("FromCSVRecord() error = %v", err)
+	}
+	secret.Store = store.NewMemoryStore()
+	versions := func() int {
+		t.Helper()
+		result, err := secret.Store.Versions(context.Background(), secret.Metadata.SecretID())
+		if err != nil {
+			t.Fatalf("Versions() error = %v", err)
+		}
+		return len(result)
+	}
+	_, _ = secret.Create(&log)
+	_, _, _ = secret.Update(true, &log)
+	if got := versions(); got != 1 {
+		t.Errorf("versions after unchanged update = %d, want 1", got)
+	}
+	secret.Data.Password = "new password"
+	_, _, _ = secret.Update(true, &log)
+	if got := versions(); got != 2 {
+		t.Errorf("versions after password update = %d, want 2", got)
+	}
 }
@@ -2,10 +2,10 @@ package get
 
 import (
 	"testing"
-	"time"
 
 	"github.com/natemarks/secret-hoard/textfile"
 
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
 )
 
@@ -22,21 +22,29 @@ func TestTextDocSecret(t *testing.T) {
 	if err != nil {
 		t.Errorf("FromCSVRecord() error = %v", err)
 	}
-	if secret.Exists(&log) {
-		tools.DeleteSecrets([]string{secret.Metadata.SecretID()})
-		t.Logf("waiting 30 seconds for secret deletion: %s", secret.Metadata.SecretID())
-		time.Sleep(30 * time.Second)
+	secret.Store = store.NewMemoryStore()
+	if exists, err := secret.Exists(&log); exists || err != nil {
+		t.Fatalf("Exists() = %v, %v in an empty store: %s", exists, err, secret.Metadata.SecretID())
 	}
 	t.Logf("creating secret: %s", secret.Metadata.SecretID())
-	secret.Create(&log)
+	if _, err = secret.Create(&log); err != nil {
+		t.Errorf("Create() error = %v", err)
+	}
 	t.Logf("updating secret - overwrite FALSE: %s", secret.Metadata.SecretID())
-	secret.Update(false, &log)
+	if action, _, err := secret.Update(false, &log); err != nil || action != store.ActionUnchanged {
+		t.Errorf("Update(false) = %v, %v, want unchanged", action, err)
+	}
 	t.Logf("updating secret - overwrite TRUE: %s", secret.Metadata.SecretID())
-	secret.Update(true, &log)
+	if action, _, err := secret.Update(true, &log); err != nil || action != store.ActionUnchanged {
+		t.Errorf("Update(true) = %v, %v, want unchanged", action, err)
+	}
 	t.Logf("downloading secret  (%s) to %s", secret.Metadata.SecretID(), downloadFile)
-	err = DownloadSecret(secret.Metadata.SecretID(), downloadFile, &log)
+	err = DownloadSecret(secret.Store, secret.Metadata.SecretID(), downloadFile, &log)
 	if err != nil {
 		t.Errorf("DownloadSecret() error = %v", err)
 	}
-	tools.DeleteSecrets([]string{secret.Metadata.SecretID()})
+	tools.DeleteSecrets(secret.Store, []string{secret.Metadata.SecretID()})
+	if exists, err := secret.Exists(&log); exists || err != nil {
+		t.Errorf("Exists() = %v, %v after delete: %s", exists, err, secret.Metadata.SecretID())
+	}
 }
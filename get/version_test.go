@@ -0,0 +1,61 @@
+package get
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+func TestDownloadSecretVersion(t *testing.T) {
+	ctx := context.Background()
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	secretID := "rdspostgres/testenv/myinstance/mydb/mytype"
+	first, err := st.Create(ctx, secretID, `{"password":"one"}`, nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.Put(ctx, secretID, `{"password":"two"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version Version
+		want    string
+	}{
+		{name: "current", version: Version{}, want: `{"password":"two"}`},
+		{name: "stage", version: Version{Stage: store.StagePrevious}, want: `{"password":"one"}`},
+		{name: "version ID", version: Version{ID: first}, want: `{"password":"one"}`},
+		{name: "version ID and stage", version: Version{ID: first, Stage: store.StagePrevious}, want: `{"password":"one"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			downloadFile := t.TempDir() + "/rdspostgres.json"
+			if err := DownloadSecretVersion(st, secretID, tt.version, downloadFile, &log); err != nil {
+				t.Fatalf("DownloadSecretVersion() error = %v", err)
+			}
+			content, err := os.ReadFile(downloadFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("DownloadSecretVersion() wrote %s, want %s", content, tt.want)
+			}
+		})
+	}
+
+	downloadFile := t.TempDir() + "/rdspostgres.json"
+	err = DownloadSecretVersion(st, secretID, Version{Stage: "AWSPENDING"}, downloadFile, &log)
+	if !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DownloadSecretVersion(AWSPENDING) error = %v, want ErrNotFound", err)
+	}
+	err = DownloadSecretVersion(st, secretID, Version{ID: first, Stage: store.StageCurrent}, downloadFile, &log)
+	if !errors.Is(err, store.ErrInvalidRequest) {
+		t.Errorf("DownloadSecretVersion() error = %v, want ErrInvalidRequest", err)
+	}
+}
@@ -1,8 +1,9 @@
 package rdspostgres
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/natemarks/secret-hoard/tools"
 	"github.com/rs/zerolog"
@@ -10,17 +11,45 @@ import (
 
 // Record is the struct of the rdspostgres record
 type Record struct {
-	ResourceType         string `json:"resourceType"`         // record[0] : rdspostgres
-	Environment          string `json:"environment"`          // record[1] : dev, integration, staging, production
-	Instance             string `json:"instance"`             // record[2] : RDS instance db identifier
-	Database             string `json:"database"`             // record[3] : database name in the instance
-	Access               string `json:"access"`               // record[4] : app_readwrite, app_readonly, etc.
-	Password             string `json:"password"`             // record[5] : password
-	Engine               string `json:"engine"`               // record[6] : ex. postgres
-	Port                 int    `json:"port"`                 // record[7] : 5432
-	DbInstanceIdentifier string `json:"dbInstanceIdentifier"` // record[8] : dbInstanceIdentifier
-	Host                 string `json:"host"`                 // record[9] : host
-	Username             string `json:"username"`             // record[10] : username
+	ResourceType         string `json:"resourceType"`             // rdspostgres
+	Environment          string `json:"environment"`              // dev, integration, staging, production
+	Instance             string `json:"instance"`                 // RDS instance db identifier
+	Database             string `json:"database"`                 // database name in the instance
+	Access               string `json:"access"`                   // app_readwrite, app_readonly, etc.
+	Password             string `json:"password"`                 // password
+	Engine               string `json:"engine"`                   // ex. postgres
+	Port                 int    `json:"port"`                     // 5432
+	DbInstanceIdentifier string `json:"dbInstanceIdentifier"`     // dbInstanceIdentifier
+	Host                 string `json:"host"`                     // host
+	Username             string `json:"username"`                 // username
+	ReplicaRegions       string `json:"replicaRegions,omitempty"` // replica regions separated by ; or spaces ex. us-west-2;eu-west-1
+	KmsKeyID             string `json:"kmsKeyId,omitempty"`       // KMS key ARN to encrypt the secret with
+	Row                  int    `json:"-"`                        // CSV row number, 0 when the record isn't read from a CSV file
+}
+
+// String formats the record for logs with the password masked
+func (r Record) String() string {
+	r.Password = tools.Mask(r.Password)
+	type plain Record // plain has no String method
+	return fmt.Sprintf("%+v", plain(r))
+}
+
+// MarshalZerologObject logs the record with the password masked
+func (r Record) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("resourceType", r.ResourceType).
+		Str("environment", r.Environment).
+		Str("instance", r.Instance).
+		Str("database", r.Database).
+		Str("access", r.Access).
+		Str("password", tools.Mask(r.Password)).
+		Str("engine", r.Engine).
+		Int("port", r.Port).
+		Str("dbInstanceIdentifier", r.DbInstanceIdentifier).
+		Str("host", r.Host).
+		Str("username", r.Username).
+		Str("replicaRegions", r.ReplicaRegions).
+		Str("kmsKeyId", r.KmsKeyID).
+		Int("row", r.Row)
 }
 
 // CSVColumns Usage output describing the CSV structure
@@ -32,38 +61,75 @@ func (r Record) CSVColumns() string {
 	return result
 }
 
-// RecordsFromCSV reads a CSV file and returns a slice of SSLCertRecords
+// Columns are the columns of the CSV file. The header must name every column in any order
+var Columns = []tools.CSVColumn{
+	{Name: "ResourceType"},
+	{Name: "Environment"},
+	{Name: "Instance"},
+	{Name: "Database"},
+	{Name: "Access"},
+	{Name: "Password"},
+	{Name: "Engine"},
+	{Name: "Port"},
+	{Name: "DbInstanceIdentifier"},
+	{Name: "Host"},
+	{Name: "Username"},
+	{Name: "ReplicaRegions", Optional: true},
+	{Name: "KmsKeyId", Optional: true},
+}
+
+// CSVValues returns the values of the record in the order of Columns
+func (r Record) CSVValues() []string {
+	return []string{r.ResourceType, r.Environment, r.Instance, r.Database, r.Access, r.Password,
+		r.Engine, strconv.Itoa(r.Port), r.DbInstanceIdentifier, r.Host, r.Username, r.ReplicaRegions, r.KmsKeyID}
+}
+
+// RecordFromCSVRow converts a CSV row read with Columns to a Record
+func RecordFromCSVRow(row tools.CSVRow) (record Record, err error) {
+	port, err := strconv.Atoi(row.Get("Port"))
+	if err != nil {
+		return record, fmt.Errorf("invalid Port %q", row.Get("Port"))
+	}
+	record = Record{
+		ResourceType:         row.Get("ResourceType"),
+		Environment:          row.Get("Environment"),
+		Instance:             row.Get("Instance"),
+		Database:             row.Get("Database"),
+		Access:               row.Get("Access"),
+		Password:             row.Get("Password"),
+		Engine:               row.Get("Engine"),
+		Port:                 port,
+		DbInstanceIdentifier: row.Get("DbInstanceIdentifier"),
+		Host:                 row.Get("Host"),
+		Username:             row.Get("Username"),
+		ReplicaRegions:       row.Get("ReplicaRegions"),
+		KmsKeyID:             row.Get("KmsKeyId"),
+		Row:                  row.Row,
+	}
+	return record, nil
+}
+
+// RecordsFromCSV reads a CSV file and returns a slice of Records. The columns are matched by
+// the header row. It returns an error for missing or unknown columns and invalid rows
 func RecordsFromCSV(csvFile string, log *zerolog.Logger) (result []Record, err error) {
-	records, err := tools.GetCSVRecordsFromFile(csvFile)
+	rows, err := tools.GetCSVRowsFromFile(csvFile, Columns)
 	if err != nil {
-		log.Error().Err(err).Msg("error reading store contents string")
+		log.Error().Err(err).Msg("error reading CSV file")
 		return result, err
 	}
 
-	for _, record := range records {
-		// skip the header row
-		if strings.ToLower(record[0]) == "resourcetype" {
-			continue
-		}
-		port, err := strconv.Atoi(record[7])
+	var errs []error
+	for _, row := range rows {
+		record, err := RecordFromCSVRow(row)
 		if err != nil {
-			log.Error().Err(err).Msgf("error converting port %s to int", record[6])
+			errs = append(errs, row.Errorf("%w", err))
 			continue
 		}
-		result = append(result, Record{
-			ResourceType:         record[0],
-			Environment:          record[1],
-			Instance:             record[2],
-			Database:             record[3],
-			Access:               record[4],
-			Password:             record[5],
-			Engine:               record[6],
-			Port:                 port,
-			DbInstanceIdentifier: record[8],
-			Host:                 record[9],
-			Username:             record[10],
-		})
-
+		result = append(result, record)
+	}
+	if err = errors.Join(errs...); err != nil {
+		log.Error().Err(err).Msgf("invalid rows in CSV file: %s", csvFile)
+		return nil, err
 	}
 	return result, nil
 }
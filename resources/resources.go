@@ -0,0 +1,123 @@
+// Package resources converts the records of every resource type to secrets and stored
+// secrets back to records
+package resources
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/natemarks/secret-hoard/jsondoc"
+	"github.com/natemarks/secret-hoard/rdspostgres"
+	"github.com/natemarks/secret-hoard/snowflake"
+	"github.com/natemarks/secret-hoard/sslcert"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/textfile"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/rs/zerolog"
+)
+
+// Writer is implemented by the Secret type of every resource package
+type Writer interface {
+	SecretID() string
+	Tags() map[string]string
+	Exists(log *zerolog.Logger) (bool, error)
+	Create(log *zerolog.Logger) (string, error)
+	Update(overwrite bool, log *zerolog.Logger) (store.Action, string, error)
+	Plan(overwrite bool, log *zerolog.Logger) (store.Change, error)
+	Restore(log *zerolog.Logger) error
+}
+
+// Secret is a Writer whose store.Options can be changed
+type Secret interface {
+	Writer
+	SecretOptions() *store.Options
+}
+
+// Type converts the records of one ResourceType to secrets and back
+type Type struct {
+	Columns []tools.CSVColumn
+	// FromCSVRow converts a CSV row read with Columns to a secret
+	FromCSVRow func(row tools.CSVRow, log *zerolog.Logger) (Secret, error)
+	// FromManifest converts a YAML or JSON manifest entry to a secret
+	FromManifest func(entry tools.ManifestEntry, log *zerolog.Logger) (Secret, error)
+	// CSVValues converts a stored secret to the values of a CSV row with Columns. The files
+	// of the record are written to dir
+	CSVValues func(description store.Description, value, dir string) ([]string, error)
+}
+
+// record is the Record type of a resource package
+type record interface {
+	CSVValues() []string
+}
+
+// secretPointer is a pointer to the Secret type S of a resource package
+type secretPointer[S any] interface {
+	*S
+	Secret
+}
+
+// newType returns the Type of a resource package. fromRow reads its Record from a CSV row,
+// convert converts the Record to its Secret and fromSecret converts a stored secret back
+func newType[R record, S any, P secretPointer[S]](
+	columns []tools.CSVColumn,
+	fromRow func(row tools.CSVRow) (R, error),
+	convert func(record R, log *zerolog.Logger) (S, error),
+	fromSecret func(tags map[string]string, value, dir string) (R, error),
+) Type {
+	return Type{
+		Columns: columns,
+		FromCSVRow: func(row tools.CSVRow, log *zerolog.Logger) (Secret, error) {
+			record, err := fromRow(row)
+			if err != nil {
+				return nil, err
+			}
+			secret, err := convert(record, log)
+			return P(&secret), err
+		},
+		FromManifest: func(entry tools.ManifestEntry, log *zerolog.Logger) (Secret, error) {
+			var record R
+			if err := entry.Decode(&record); err != nil {
+				return nil, err
+			}
+			secret, err := convert(record, log)
+			return P(&secret), err
+		},
+		CSVValues: func(description store.Description, value, dir string) ([]string, error) {
+			record, err := fromSecret(description.Tags, value, dir)
+			values := record.CSVValues()
+			for i, column := range columns {
+				switch column.Name {
+				case "ReplicaRegions":
+					values[i] = strings.Join(description.ReplicaRegions(), ";")
+				case "KmsKeyId":
+					values[i] = description.KmsKeyID
+				}
+			}
+			return values, err
+		},
+	}
+}
+
+// types are the resource types by ResourceType value
+var types = map[string]Type{
+	"rdspostgres":     newType(rdspostgres.Columns, rdspostgres.RecordFromCSVRow, rdspostgres.FromCSVRecord, rdspostgres.RecordFromSecret),
+	"snowflake":       newType(snowflake.Columns, snowflake.RecordFromCSVRow, snowflake.FromCSVRecord, snowflake.RecordFromSecret),
+	"ssl_certificate": newType(sslcert.Columns, sslcert.RecordFromCSVRow, sslcert.FromCSVRecord, sslcert.RecordFromSecret),
+	"jsondoc":         newType(jsondoc.Columns, jsondoc.RecordFromCSVRow, jsondoc.FromCSVRecord, jsondoc.RecordFromSecret),
+	"text_file":       newType(textfile.Columns, textfile.RecordFromCSVRow, textfile.FromCSVRecord, textfile.RecordFromSecret),
+}
+
+// Lookup returns the resource type of a ResourceType value
+func Lookup(name string) (resource Type, ok bool) {
+	resource, ok = types[name]
+	return resource, ok
+}
+
+// Names returns the supported ResourceType values
+func Names() (result []string) {
+	for name := range types {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,574 @@
+// Package smserver is a small HTTP server that speaks enough of the AWS Secrets Manager
+// JSON protocol to run the secret-hoard commands against any store.SecretStore. Point the
+// aws-sdk-go-v2 clients at it with AWS_ENDPOINT_URL. STS GetCallerIdentity is answered too
+// because the commands log the AWS account number
+package smserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/natemarks/secret-hoard/store"
+)
+
+// AccountID is the account number returned by GetCallerIdentity
+const AccountID = "000000000000"
+
+// targetPrefix is the X-Amz-Target prefix of Secrets Manager requests
+const targetPrefix = "secretsmanager."
+
+// apiError is a Secrets Manager error response
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.code, e.message)
+}
+
+// Server implements http.Handler for the Secrets Manager JSON protocol
+type Server struct {
+	Store      store.SecretStore
+	operations map[string]func(ctx context.Context, body []byte) (any, error)
+}
+
+// New returns a Server backed by st
+func New(st store.SecretStore) *Server {
+	s := &Server{Store: st}
+	s.operations = map[string]func(ctx context.Context, body []byte) (any, error){
+		"CreateSecret":                 s.createSecret,
+		"UpdateSecret":                 s.updateSecret,
+		"DescribeSecret":               s.describeSecret,
+		"GetSecretValue":               s.getSecretValue,
+		"TagResource":                  s.tagResource,
+		"DeleteSecret":                 s.deleteSecret,
+		"RestoreSecret":                s.restoreSecret,
+		"ListSecrets":                  s.listSecrets,
+		"ListSecretVersionIds":         s.listSecretVersionIDs,
+		"UpdateSecretVersionStage":     s.updateSecretVersionStage,
+		"ReplicateSecretToRegions":     s.replicateSecretToRegions,
+		"RemoveRegionsFromReplication": s.removeRegionsFromReplication,
+	}
+	return s
+}
+
+// ServeHTTP dispatches requests on the X-Amz-Target header
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, apiError{http.StatusBadRequest, "InvalidRequestException", err.Error()})
+		return
+	}
+	target := r.Header.Get("X-Amz-Target")
+	if target == "" {
+		s.serveSTS(w, body)
+		return
+	}
+	operation, ok := s.operations[strings.TrimPrefix(target, targetPrefix)]
+	if !ok || !strings.HasPrefix(target, targetPrefix) {
+		writeError(w, apiError{http.StatusBadRequest, "UnknownOperationException", "unsupported operation: " + target})
+		return
+	}
+	result, err := operation(r.Context(), body)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+// serveSTS answers GetCallerIdentity with AccountID
+func (s *Server) serveSTS(w http.ResponseWriter, body []byte) {
+	if !strings.Contains(string(body), "Action=GetCallerIdentity") {
+		writeError(w, apiError{http.StatusBadRequest, "UnknownOperationException", "missing X-Amz-Target"})
+		return
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = fmt.Fprintf(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:iam::%[1]s:user/secret-hoard</Arn>
+    <UserId>secret-hoard</UserId>
+    <Account>%[1]s</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata><RequestId>secret-hoard</RequestId></ResponseMetadata>
+</GetCallerIdentityResponse>`, AccountID)
+}
+
+// writeError converts store errors to Secrets Manager error responses
+func writeError(w http.ResponseWriter, err error) {
+	var e apiError
+	switch {
+	case errors.As(err, &e):
+	case errors.Is(err, store.ErrNotFound):
+		e = apiError{http.StatusBadRequest, "ResourceNotFoundException", err.Error()}
+	case errors.Is(err, store.ErrExists):
+		e = apiError{http.StatusBadRequest, "ResourceExistsException", err.Error()}
+	case errors.Is(err, store.ErrScheduledForDeletion):
+		// Secrets Manager rejects operations on secrets scheduled for deletion as invalid requests
+		e = apiError{http.StatusBadRequest, "InvalidRequestException", err.Error()}
+	case errors.Is(err, store.ErrInvalidRequest):
+		e = apiError{http.StatusBadRequest, "InvalidParameterException", err.Error()}
+	default:
+		e = apiError{http.StatusInternalServerError, "InternalServiceError", err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Header().Set("X-Amzn-ErrorType", e.code)
+	w.WriteHeader(e.status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"__type": e.code, "Message": e.message})
+}
+
+// decode unmarshals a request body
+func decode(body []byte, v any) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return apiError{http.StatusBadRequest, "InvalidRequestException", err.Error()}
+	}
+	return nil
+}
+
+// secretName accepts a secret name or ARN
+func secretName(secretID string) string {
+	if _, name, ok := strings.Cut(secretID, ":secret:"); ok && strings.HasPrefix(secretID, "arn:") {
+		return name
+	}
+	return secretID
+}
+
+// epoch converts a time to the epoch seconds used by the JSON protocol
+func epoch(t *time.Time) *float64 {
+	if t == nil {
+		return nil
+	}
+	seconds := float64(t.UnixNano()) / float64(time.Second)
+	return &seconds
+}
+
+// tag is the JSON form of a secret tag
+type tag struct {
+	Key   string `json:"Key"`
+	Value string `json:"Value"`
+}
+
+func tagsToMap(tags []tag) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, t := range tags {
+		result[t.Key] = t.Value
+	}
+	return result
+}
+
+func mapToTags(tags map[string]string) []tag {
+	result := []tag{}
+	for key, value := range tags {
+		result = append(result, tag{Key: key, Value: value})
+	}
+	return result
+}
+
+// secretRequest is the common part of the requests that name a secret
+type secretRequest struct {
+	SecretID string `json:"SecretId"`
+}
+
+// secretResponse is the common part of the responses that name a secret
+type secretResponse struct {
+	ARN       string `json:"ARN"`
+	Name      string `json:"Name"`
+	VersionID string `json:"VersionId,omitempty"`
+}
+
+// response describes the secret for the responses that only name the secret
+func (s *Server) response(ctx context.Context, name, versionID string) (secretResponse, error) {
+	description, err := s.Store.Describe(ctx, name)
+	if err != nil {
+		return secretResponse{}, err
+	}
+	return secretResponse{ARN: description.ARN, Name: description.Name, VersionID: versionID}, nil
+}
+
+// replicaRegion is a region of the AddReplicaRegions parameter
+type replicaRegion struct {
+	Region string `json:"Region"`
+}
+
+func replicaRegions(replicas []replicaRegion) []string {
+	result := make([]string, len(replicas))
+	for i, replica := range replicas {
+		result[i] = replica.Region
+	}
+	return result
+}
+
+// replicationStatus is an element of the ReplicationStatus response field
+type replicationStatus struct {
+	Region        string `json:"Region"`
+	Status        string `json:"Status"`
+	StatusMessage string `json:"StatusMessage,omitempty"`
+}
+
+func replicationStatuses(replicas []store.Replica) []replicationStatus {
+	result := []replicationStatus{}
+	for _, replica := range replicas {
+		result = append(result, replicationStatus{replica.Region, replica.Status, replica.StatusMessage})
+	}
+	return result
+}
+
+func (s *Server) createSecret(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		Name              string `json:"Name"`
+		SecretString      *string
+		Tags              []tag           `json:"Tags"`
+		AddReplicaRegions []replicaRegion `json:"AddReplicaRegions"`
+		KmsKeyID          string          `json:"KmsKeyId"`
+		Token             string          `json:"ClientRequestToken"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	ctx = store.WithClientRequestToken(ctx, input.Token)
+	if input.SecretString == nil {
+		return nil, apiError{http.StatusBadRequest, "InvalidParameterException", "SecretString is required"}
+	}
+	versionID, err := s.Store.Create(ctx, input.Name, *input.SecretString, tagsToMap(input.Tags), input.KmsKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.AddReplicaRegions) > 0 {
+		if err = s.Store.ReplicateRegions(ctx, input.Name, replicaRegions(input.AddReplicaRegions)); err != nil {
+			return nil, err
+		}
+	}
+	return s.response(ctx, input.Name, versionID)
+}
+
+func (s *Server) updateSecret(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		secretRequest
+		SecretString *string
+		KmsKeyID     string `json:"KmsKeyId"`
+		Token        string `json:"ClientRequestToken"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	if input.KmsKeyID != "" {
+		if err := s.Store.SetKMSKey(ctx, name, input.KmsKeyID); err != nil {
+			return nil, err
+		}
+	}
+	if input.SecretString == nil {
+		return s.response(ctx, name, "")
+	}
+	versionID, err := s.Store.Put(store.WithClientRequestToken(ctx, input.Token), name, *input.SecretString)
+	if err != nil {
+		return nil, err
+	}
+	return s.response(ctx, name, versionID)
+}
+
+func (s *Server) describeSecret(ctx context.Context, body []byte) (any, error) {
+	var input secretRequest
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	description, err := s.Store.Describe(ctx, secretName(input.SecretID))
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		ARN                string              `json:"ARN"`
+		Name               string              `json:"Name"`
+		Tags               []tag               `json:"Tags"`
+		LastChangedDate    *float64            `json:"LastChangedDate,omitempty"`
+		DeletedDate        *float64            `json:"DeletedDate,omitempty"`
+		VersionIdsToStages map[string][]string `json:"VersionIdsToStages"`
+		ReplicationStatus  []replicationStatus `json:"ReplicationStatus,omitempty"`
+		KmsKeyID           string              `json:"KmsKeyId,omitempty"`
+	}{
+		ARN:                description.ARN,
+		Name:               description.Name,
+		Tags:               mapToTags(description.Tags),
+		LastChangedDate:    epoch(description.LastChangedDate),
+		DeletedDate:        epoch(description.DeletedDate),
+		VersionIdsToStages: description.VersionStages,
+		ReplicationStatus:  replicationStatuses(description.Replicas),
+		KmsKeyID:           description.KmsKeyID,
+	}, nil
+}
+
+func (s *Server) getSecretValue(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		secretRequest
+		VersionID    string `json:"VersionId"`
+		VersionStage string `json:"VersionStage"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	value, err := s.Store.GetVersion(ctx, name, input.VersionID, input.VersionStage)
+	if err != nil {
+		return nil, err
+	}
+	description, err := s.Store.Describe(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	versionID := input.VersionID
+	if versionID == "" {
+		versionID = description.StageVersionID(input.VersionStage)
+	}
+	return struct {
+		ARN           string   `json:"ARN"`
+		Name          string   `json:"Name"`
+		SecretString  string   `json:"SecretString"`
+		VersionID     string   `json:"VersionId"`
+		VersionStages []string `json:"VersionStages"`
+	}{
+		ARN:           description.ARN,
+		Name:          description.Name,
+		SecretString:  value,
+		VersionID:     versionID,
+		VersionStages: description.VersionStages[versionID],
+	}, nil
+}
+
+func (s *Server) tagResource(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		secretRequest
+		Tags []tag `json:"Tags"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	return struct{}{}, s.Store.Tag(ctx, secretName(input.SecretID), tagsToMap(input.Tags))
+}
+
+func (s *Server) deleteSecret(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		secretRequest
+		RecoveryWindowInDays       int  `json:"RecoveryWindowInDays"`
+		ForceDeleteWithoutRecovery bool `json:"ForceDeleteWithoutRecovery"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	response, err := s.response(ctx, name, "")
+	if err != nil {
+		return nil, err
+	}
+	deletionDate := time.Now()
+	if input.ForceDeleteWithoutRecovery {
+		err = s.Store.Delete(ctx, name)
+	} else {
+		if input.RecoveryWindowInDays == 0 {
+			input.RecoveryWindowInDays = store.DefaultRecoveryWindowDays
+		}
+		deletionDate, err = s.Store.ScheduleDeletion(ctx, name, input.RecoveryWindowInDays)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		secretResponse
+		DeletionDate *float64 `json:"DeletionDate"`
+	}{response, epoch(&deletionDate)}, nil
+}
+
+func (s *Server) restoreSecret(ctx context.Context, body []byte) (any, error) {
+	var input secretRequest
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	if err := s.Store.Restore(ctx, name); err != nil {
+		return nil, err
+	}
+	return s.response(ctx, name, "")
+}
+
+// filter is a ListSecrets filter
+type filter struct {
+	Key    string   `json:"Key"`
+	Values []string `json:"Values"`
+}
+
+// matches applies a ListSecrets filter. Values are prefixes and a leading ! negates them
+func (f filter) matches(description store.Description) bool {
+	var candidates []string
+	switch f.Key {
+	case "name":
+		candidates = []string{description.Name}
+	case "tag-key":
+		for key := range description.Tags {
+			candidates = append(candidates, key)
+		}
+	case "tag-value":
+		for _, value := range description.Tags {
+			candidates = append(candidates, value)
+		}
+	case "all":
+		candidates = []string{description.Name}
+		for key, value := range description.Tags {
+			candidates = append(candidates, key, value)
+		}
+	default:
+		return true
+	}
+	for _, value := range f.Values {
+		negate := strings.HasPrefix(value, "!")
+		value = strings.TrimPrefix(value, "!")
+		found := false
+		for _, candidate := range candidates {
+			if strings.HasPrefix(candidate, value) {
+				found = true
+			}
+		}
+		if found != negate {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) listSecrets(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		Filters []filter `json:"Filters"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	descriptions, err := s.Store.List(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	type entry struct {
+		ARN                    string              `json:"ARN"`
+		Name                   string              `json:"Name"`
+		Tags                   []tag               `json:"Tags"`
+		LastChangedDate        *float64            `json:"LastChangedDate,omitempty"`
+		DeletedDate            *float64            `json:"DeletedDate,omitempty"`
+		SecretVersionsToStages map[string][]string `json:"SecretVersionsToStages"`
+	}
+	secretList := []entry{}
+	for _, description := range descriptions {
+		matched := true
+		for _, f := range input.Filters {
+			matched = matched && f.matches(description)
+		}
+		if !matched {
+			continue
+		}
+		secretList = append(secretList, entry{
+			ARN:                    description.ARN,
+			Name:                   description.Name,
+			Tags:                   mapToTags(description.Tags),
+			LastChangedDate:        epoch(description.LastChangedDate),
+			DeletedDate:            epoch(description.DeletedDate),
+			SecretVersionsToStages: description.VersionStages,
+		})
+	}
+	return struct {
+		SecretList []entry `json:"SecretList"`
+	}{secretList}, nil
+}
+
+func (s *Server) listSecretVersionIDs(ctx context.Context, body []byte) (any, error) {
+	var input secretRequest
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	response, err := s.response(ctx, name, "")
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.Store.Versions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	type entry struct {
+		VersionID     string   `json:"VersionId"`
+		VersionStages []string `json:"VersionStages"`
+		CreatedDate   *float64 `json:"CreatedDate,omitempty"`
+	}
+	entries := []entry{}
+	for _, version := range versions {
+		entries = append(entries, entry{version.VersionID, version.Stages, epoch(version.CreatedDate)})
+	}
+	return struct {
+		secretResponse
+		Versions []entry `json:"Versions"`
+	}{response, entries}, nil
+}
+
+func (s *Server) updateSecretVersionStage(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		secretRequest
+		VersionStage        string `json:"VersionStage"`
+		MoveToVersionID     string `json:"MoveToVersionId"`
+		RemoveFromVersionID string `json:"RemoveFromVersionId"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	err := s.Store.UpdateVersionStage(ctx, name, input.VersionStage, input.MoveToVersionID, input.RemoveFromVersionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.response(ctx, name, "")
+}
+
+// replicationResponse describes the replicas of a secret after they changed
+func (s *Server) replicationResponse(ctx context.Context, name string) (any, error) {
+	description, err := s.Store.Describe(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return struct {
+		ARN               string              `json:"ARN"`
+		ReplicationStatus []replicationStatus `json:"ReplicationStatus"`
+	}{description.ARN, replicationStatuses(description.Replicas)}, nil
+}
+
+func (s *Server) replicateSecretToRegions(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		secretRequest
+		AddReplicaRegions []replicaRegion `json:"AddReplicaRegions"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	if err := s.Store.ReplicateRegions(ctx, name, replicaRegions(input.AddReplicaRegions)); err != nil {
+		return nil, err
+	}
+	return s.replicationResponse(ctx, name)
+}
+
+func (s *Server) removeRegionsFromReplication(ctx context.Context, body []byte) (any, error) {
+	var input struct {
+		secretRequest
+		RemoveReplicaRegions []string `json:"RemoveReplicaRegions"`
+	}
+	if err := decode(body, &input); err != nil {
+		return nil, err
+	}
+	name := secretName(input.SecretID)
+	if err := s.Store.RemoveReplicaRegions(ctx, name, input.RemoveReplicaRegions); err != nil {
+		return nil, err
+	}
+	return s.replicationResponse(ctx, name)
+}
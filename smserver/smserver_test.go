@@ -0,0 +1,287 @@
+package smserver
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/natemarks/secret-hoard/export"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+// testClient returns a SecretsManager store using a real SDK client pointed at the server
+func testClient(url string) store.SecretsManager {
+	return store.SecretsManager{Client: secretsmanager.New(secretsmanager.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(url),
+		Credentials:  aws.AnonymousCredentials{},
+	})}
+}
+
+func TestSecretsManagerClient(t *testing.T) {
+	server := httptest.NewServer(New(store.NewMemoryStore()))
+	defer server.Close()
+	ctx := context.Background()
+	st := testClient(server.URL)
+	secretID := "snowflake/testenv/mywarehouse/mytype"
+
+	if _, err := st.Describe(ctx, secretID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Describe() error = %v, want ErrNotFound", err)
+	}
+	first, err := st.Create(ctx, secretID, "one", map[string]string{"Source": "secret-hoard"}, "")
+	if err != nil || first == "" {
+		t.Fatalf("Create() = %q, %v", first, err)
+	}
+	if _, err = st.Create(ctx, secretID, "one", nil, ""); !errors.Is(err, store.ErrExists) {
+		t.Fatalf("Create() error = %v, want ErrExists", err)
+	}
+	second, err := st.Put(ctx, secretID, "two")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if err = st.Tag(ctx, secretID, map[string]string{"Environment": "testenv"}); err != nil {
+		t.Fatalf("Tag() error = %v", err)
+	}
+	description, err := st.Describe(ctx, secretID)
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	wantTags := map[string]string{"Source": "secret-hoard", "Environment": "testenv"}
+	if !reflect.DeepEqual(description.Tags, wantTags) || description.LastChangedDate == nil {
+		t.Errorf("Describe() = %+v", description)
+	}
+	listed, err := st.List(ctx, wantTags)
+	if err != nil || len(listed) != 1 {
+		t.Fatalf("List() = %v, %v", listed, err)
+	}
+
+	// roll back AWSCURRENT to the first version
+	if err = st.UpdateVersionStage(ctx, secretID, store.StageCurrent, first, second); err != nil {
+		t.Fatalf("UpdateVersionStage() error = %v", err)
+	}
+	if value, err := st.Get(ctx, secretID); err != nil || value != "one" {
+		t.Fatalf("Get() = %q, %v, want \"one\"", value, err)
+	}
+	if value, err := st.GetVersion(ctx, secretID, second, ""); err != nil || value != "two" {
+		t.Fatalf("GetVersion(%s) = %q, %v, want \"two\"", second, value, err)
+	}
+	if value, err := st.GetVersion(ctx, secretID, "", store.StagePrevious); err != nil || value != "two" {
+		t.Fatalf("GetVersion(AWSPREVIOUS) = %q, %v, want \"two\"", value, err)
+	}
+	versions, err := st.Versions(ctx, secretID)
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("Versions() = %v, %v", versions, err)
+	}
+	if err = st.UpdateVersionStage(ctx, secretID, store.StageCurrent, second, ""); !errors.Is(err, store.ErrInvalidRequest) {
+		t.Fatalf("UpdateVersionStage() error = %v, want ErrInvalidRequest", err)
+	}
+
+	if err = st.ReplicateRegions(ctx, secretID, []string{"us-west-2", "eu-west-1"}); err != nil {
+		t.Fatalf("ReplicateRegions() error = %v", err)
+	}
+	if err = st.RemoveReplicaRegions(ctx, secretID, []string{"eu-west-1"}); err != nil {
+		t.Fatalf("RemoveReplicaRegions() error = %v", err)
+	}
+	description, err = st.Describe(ctx, secretID)
+	if err != nil || !reflect.DeepEqual(description.Replicas, []store.Replica{{Region: "us-west-2", Status: "InSync"}}) {
+		t.Fatalf("Describe() replicas = %+v, %v", description.Replicas, err)
+	}
+
+	kmsKeyID := "arn:aws:kms:us-east-1:000000000000:key/testenv"
+	if err = st.SetKMSKey(ctx, secretID, kmsKeyID); err != nil {
+		t.Fatalf("SetKMSKey() error = %v", err)
+	}
+	if description, err = st.Describe(ctx, secretID); err != nil || description.KmsKeyID != kmsKeyID {
+		t.Fatalf("Describe() KMS key = %q, %v, want %q", description.KmsKeyID, err, kmsKeyID)
+	}
+
+	deletionDate, err := st.ScheduleDeletion(ctx, secretID, store.MinRecoveryWindowDays)
+	if err != nil || time.Until(deletionDate) < 6*24*time.Hour {
+		t.Fatalf("ScheduleDeletion() = %v, %v", deletionDate, err)
+	}
+	if description, err = st.Describe(ctx, secretID); err != nil || !description.ScheduledForDeletion() {
+		t.Fatalf("Describe() = %+v, %v, want scheduled for deletion", description, err)
+	}
+	// Secrets Manager rejects them with InvalidRequestException
+	if _, err = st.Get(ctx, secretID); !errors.Is(err, store.ErrScheduledForDeletion) || !errors.Is(err, store.ErrInvalidRequest) {
+		t.Fatalf("Get() error = %v, want ErrScheduledForDeletion", err)
+	}
+	if _, err = st.Create(ctx, secretID, "value", nil, ""); !errors.Is(err, store.ErrScheduledForDeletion) {
+		t.Fatalf("Create() error = %v, want ErrScheduledForDeletion", err)
+	}
+	if err = st.Restore(ctx, secretID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if err = st.Delete(ctx, secretID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err = st.Get(ctx, secretID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+// invalidListStore rejects every List and Versions call
+type invalidListStore struct {
+	*store.MemoryStore
+}
+
+// List returns an invalid request error
+func (invalidListStore) List(context.Context, map[string]string) ([]store.Description, error) {
+	return nil, store.ErrInvalidRequest
+}
+
+// Versions returns an invalid request error
+func (invalidListStore) Versions(context.Context, string) ([]store.Version, error) {
+	return nil, store.ErrInvalidRequest
+}
+
+// TestSecretsManagerPageErrors checks that List and Versions page errors map to the store errors
+func TestSecretsManagerPageErrors(t *testing.T) {
+	mem := store.NewMemoryStore()
+	ctx := context.Background()
+	if _, err := mem.Create(ctx, "one", "value", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(New(invalidListStore{mem}))
+	defer server.Close()
+	st := testClient(server.URL)
+	if _, err := st.List(ctx, nil); !errors.Is(err, store.ErrInvalidRequest) {
+		t.Errorf("List() error = %v, want ErrInvalidRequest", err)
+	}
+	if _, err := st.Versions(ctx, "one"); !errors.Is(err, store.ErrInvalidRequest) {
+		t.Errorf("Versions() error = %v, want ErrInvalidRequest", err)
+	}
+}
+
+// TestBinaries builds the commands and runs them against the server
+func TestBinaries(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the commands")
+	}
+	mem := store.NewMemoryStore()
+	server := httptest.NewServer(New(mem))
+	defer server.Close()
+	binDir := t.TempDir()
+	env := append(os.Environ(),
+		"AWS_ENDPOINT_URL="+server.URL,
+		"AWS_REGION=us-east-1",
+		"AWS_ACCESS_KEY_ID=secret-hoard",
+		"AWS_SECRET_ACCESS_KEY=secret-hoard",
+		"AWS_CONFIG_FILE=/dev/null",
+		"AWS_SHARED_CREDENTIALS_FILE=/dev/null",
+		"SECRET_HOARD_PASSPHRASE=secret-hoard",
+	)
+	runEnv := func(env []string, name string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(name, args...)
+		cmd.Dir = ".."
+		cmd.Env = env
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %v: %v\n%s", name, args, err, output)
+		}
+		return string(output)
+	}
+	run := func(name string, args ...string) string {
+		t.Helper()
+		return runEnv(env, name, args...)
+	}
+	for _, command := range []string{"sh-upload", "sh-download", "sh-rollback", "sh-list", "sh-diff", "sh-backup", "sh-restore", "sh-copy", "sh-export"} {
+		run("go", "build", "-o", filepath.Join(binDir, command), "./cmd/"+command)
+	}
+
+	run(filepath.Join(binDir, "sh-upload"), "-file=examples/textfile_example.csv")
+	downloadFile := filepath.Join(t.TempDir(), "text_file_example.txt")
+	run(filepath.Join(binDir, "sh-download"), "-secret=text_file/testenv/my_file_type", "-file="+downloadFile)
+
+	got, err := os.ReadFile(downloadFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("../examples/text_file_example.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("downloaded %q, want %q", got, want)
+	}
+
+	if output := run(filepath.Join(binDir, "sh-diff"), "-file=examples/textfile_example.csv"); !strings.Contains(output, "(in sync)") {
+		t.Errorf("sh-diff output = %s", output)
+	}
+	output := run(filepath.Join(binDir, "sh-list"), "-resource-type=text_file", "-format=csv")
+	if !strings.Contains(output, "SecretID,LastChangedDate,VersionCount,Tags") || !strings.Contains(output, "text_file/testenv/my_file_type,") {
+		t.Errorf("sh-list output = %s", output)
+	}
+	archiveFile := filepath.Join(t.TempDir(), "backup.age")
+	run(filepath.Join(binDir, "sh-backup"), "-resource-type=text_file", "-file="+archiveFile)
+	if output = run(filepath.Join(binDir, "sh-restore"), "-file="+archiveFile, "-verify"); !strings.Contains(output, "with 1 secrets") {
+		t.Errorf("sh-restore -verify output = %s", output)
+	}
+
+	// roll back a new value
+	secretID := "text_file/testenv/my_file_type"
+	if _, err = mem.Put(context.Background(), secretID, `{"contents":"new"}`); err != nil {
+		t.Fatal(err)
+	}
+	previousFile := filepath.Join(t.TempDir(), "text_file_previous.txt")
+	run(filepath.Join(binDir, "sh-download"), "-secret="+secretID, "-version-stage=AWSPREVIOUS", "-file="+previousFile)
+	if got, err = os.ReadFile(previousFile); err != nil || string(got) != string(want) {
+		t.Errorf("downloaded AWSPREVIOUS %q, %v, want %q", got, err, want)
+	}
+	output = run(filepath.Join(binDir, "sh-rollback"), "-secret="+secretID)
+	if !strings.Contains(output, "before:") || !strings.Contains(output, "after:") {
+		t.Errorf("sh-rollback output = %s", output)
+	}
+	value, err := mem.Get(context.Background(), secretID)
+	if err != nil || strings.Contains(value, "new") {
+		t.Errorf("Get() = %q, %v after sh-rollback", value, err)
+	}
+
+	// export the rolled back value
+	exportDir := filepath.Join(t.TempDir(), "export")
+	run(filepath.Join(binDir, "sh-export"), "-resource-type=text_file", "-dir="+exportDir)
+	if got, err = os.ReadFile(export.CSVPath(exportDir, "text_file")); err != nil || !strings.Contains(string(got), "text_file,testenv,my_file_type,") {
+		t.Errorf("exported CSV = %s, %v", got, err)
+	}
+	if got, err = os.ReadFile(filepath.Join(exportDir, export.FilesDir, tools.SecretFileName(secretID))); err != nil || string(got) != string(want) {
+		t.Errorf("exported file = %q, %v, want %q", got, err, want)
+	}
+
+	// copy the secret to a destination profile with its own server. The profiles set the
+	// endpoints and credentials, so AWS_ENDPOINT_URL is left out
+	dst := store.NewMemoryStore()
+	dstServer := httptest.NewServer(New(dst))
+	defer dstServer.Close()
+	configFile := filepath.Join(t.TempDir(), "config")
+	var profiles string
+	for name, url := range map[string]string{"source": server.URL, "destination": dstServer.URL} {
+		profiles += "[profile " + name + "]\nendpoint_url = " + url +
+			"\naws_access_key_id = secret-hoard\naws_secret_access_key = secret-hoard\n"
+	}
+	if err = os.WriteFile(configFile, []byte(profiles), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	copyEnv := append(slices.DeleteFunc(slices.Clone(env), func(variable string) bool {
+		return strings.HasPrefix(variable, "AWS_ENDPOINT_URL=") || strings.HasPrefix(variable, "AWS_CONFIG_FILE=")
+	}), "AWS_CONFIG_FILE="+configFile)
+	runEnv(copyEnv, filepath.Join(binDir, "sh-copy"), "-source-profile=source", "-destination-profile=destination", "-secret="+secretID)
+	copied, err := dst.Get(context.Background(), secretID)
+	if err != nil || copied != value {
+		t.Errorf("copied value = %q, %v, want %q", copied, err, value)
+	}
+	if description, err := dst.Describe(context.Background(), secretID); err != nil || description.Tags["Environment"] != "testenv" {
+		t.Errorf("copied tags = %v, %v", description.Tags, err)
+	}
+}
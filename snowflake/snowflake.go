@@ -1,16 +1,12 @@
 package snowflake
 
 import (
-	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
+	"github.com/natemarks/secret-hoard/writer"
 	"github.com/rs/zerolog"
 )
 
@@ -51,132 +47,100 @@ type Data struct {
 	Username    string `json:"username"`
 }
 
+// String formats the secret data for logs with the password masked
+func (d Data) String() string {
+	d.Password = tools.Mask(d.Password)
+	type plain Data // plain has no String method
+	return fmt.Sprintf("%+v", plain(d))
+}
+
+// MarshalZerologObject logs the secret data with the password masked
+func (d Data) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("password", tools.Mask(d.Password)).
+		Str("accountName", d.AccountName).
+		Str("warehouse", d.Warehouse).
+		Str("username", d.Username)
+}
+
 // Secret is the struct of the secret generated for RDS by CDK deployment
 type Secret struct {
 	Data     Data
 	Metadata Metadata
+	store.Options
 }
 
-// Exists checks if the secret exists in Secrets Manager
-func (s Secret) Exists(log *zerolog.Logger) bool {
-
-	// Load AWS SDK configuration
-	cfg, err := config.LoadDefaultConfig(context.Background())
-	if err != nil {
-		log.Fatal().Err(err).Msg("unable to load SDK config")
-	}
-
-	// Create Secrets Manager client
-	client := secretsmanager.NewFromConfig(cfg)
+// SecretID returns the secret id
+func (s Secret) SecretID() string {
+	return s.Metadata.SecretID()
+}
 
-	// Input parameters for DescribeSecret API call
-	input := &secretsmanager.DescribeSecretInput{
-		SecretId: aws.String(s.Metadata.SecretID()),
-	}
+// Tags returns the tags of the secret
+func (s Secret) Tags() map[string]string {
+	return s.Metadata.Map()
+}
 
-	// Call DescribeSecret API to check if the secret exists
-	_, err = client.DescribeSecret(context.Background(), input)
-	if err != nil {
-		var e *types.ResourceNotFoundException
-		if errors.As(err, &e) {
-			log.Debug().Msgf("secret does not exist: %s", *input.SecretId)
-			return false
-		}
-	}
-	log.Debug().Msgf("secret exists: %s", *input.SecretId)
-	return true
+// Exists returns true if the secret exists. Errors other than not found are returned. A
+// secret scheduled for deletion exists and the error wraps store.ErrScheduledForDeletion
+func (s Secret) Exists(log *zerolog.Logger) (bool, error) {
+	return writer.Exists(s.Options, s.Metadata.SecretID(), log)
 }
 
 // Create the secret in secretsmanager
-func (s Secret) Create(log *zerolog.Logger) {
+func (s Secret) Create(log *zerolog.Logger) (versionID string, err error) {
 	log.Debug().Msgf("creating snowflake secret: %s", s.Metadata.SecretID())
-	ctx := context.Background()
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		panic("configuration error, " + err.Error())
-	}
 
-	client := secretsmanager.NewFromConfig(cfg)
-
-	// Convert RDSSecretData to JSON string
+	// Convert Data to JSON string
 	secretValue, err := json.Marshal(s.Data)
 	if err != nil {
 		log.Error().Err(err).Msg("error marshalling secret data")
-		return
-	}
-
-	// Convert RDSSecretMetadata to tags
-	tags := s.Metadata.Map()
-
-	// Create the secret
-	createSecretInput := &secretsmanager.CreateSecretInput{
-		Name:         aws.String(fmt.Sprint(s.Metadata.SecretID())),
-		SecretString: aws.String(string(secretValue)),
-		Tags:         tools.ConvertMapToTags(tags),
-	}
-	_, err = client.CreateSecret(ctx, createSecretInput)
-	// If the secret already exists and overwrite is true, update it
-	if err != nil {
-		log.Error().Err(err).Msgf("error creating snowflake secret: %s", *createSecretInput.Name)
-		return
+		return "", err
 	}
-	log.Info().Msgf("secret created successfully: %s", *createSecretInput.Name)
+	return writer.Create(s.Options, s.Metadata.SecretID(), string(secretValue), s.Metadata.Map(), log)
 }
 
-// Update the RDS secret
-func (s Secret) Update(overwrite bool, log *zerolog.Logger) {
-	if !overwrite {
-		log.Debug().Msgf("overwrite is false, skipping update for %s", s.Metadata.SecretID())
-		return
-	}
-	ctx := context.Background()
-	cfg, err := config.LoadDefaultConfig(ctx)
-	if err != nil {
-		panic("configuration error, " + err.Error())
-	}
-
-	client := secretsmanager.NewFromConfig(cfg)
+// Restore cancels the scheduled deletion of the secret so it can be updated
+func (s Secret) Restore(log *zerolog.Logger) error {
+	return writer.Restore(s.Options, s.Metadata.SecretID(), log)
+}
 
-	// Convert RDSSecretData to JSON string
+// Update the RDS secret. Nothing is written when the stored value and tags already match.
+// The action is update, unchanged or skip (overwrite is false) and versionID is the
+// resulting AWSCURRENT version
+func (s Secret) Update(overwrite bool, log *zerolog.Logger) (action store.Action, versionID string, err error) {
+	// Convert Data to JSON string
 	secretValue, err := json.Marshal(s.Data)
 	if err != nil {
 		log.Error().Err(err).Msg("error marshalling secret data")
-		return
-	}
-
-	// Convert RDSSecretMetadata to tags
-	tags := s.Metadata.Map()
-
-	// Create the secret
-	// Update the secret string value
-	updateSecretInput := &secretsmanager.UpdateSecretInput{
-		SecretId:     aws.String(fmt.Sprint(s.Metadata.SecretID())),
-		SecretString: aws.String(string(secretValue)),
-	}
-	_, err = client.UpdateSecret(ctx, updateSecretInput)
-	// If the secret already exists and overwrite is true, update it
-	if err != nil {
-		log.Error().Err(err).Msgf("error updating secret value: %s", *updateSecretInput.SecretId)
-		return
+		return "", "", err
 	}
+	return writer.Update(s.Options, s.Metadata.SecretID(), string(secretValue), s.Metadata.Map(), overwrite, log)
+}
 
-	// Update the secret tags
-	tagResourceInput := &secretsmanager.TagResourceInput{
-		SecretId: aws.String(fmt.Sprint(s.Metadata.SecretID())),
-		Tags:     tools.ConvertMapToTags(tags),
-	}
-	_, err = client.TagResource(ctx, tagResourceInput)
+// Plan returns the change Create or Update would make without writing anything
+func (s Secret) Plan(overwrite bool, log *zerolog.Logger) (change store.Change, err error) {
+	change.SecretID = s.Metadata.SecretID()
+	secretValue, err := json.Marshal(s.Data)
 	if err != nil {
-		log.Error().Err(err).Msgf("error updating secret tags: %s", *updateSecretInput.SecretId)
-		return
+		return change, err
 	}
-	log.Info().Msgf("secret update successfully: %s", *updateSecretInput.SecretId)
+	return writer.Plan(s.Options, s.Metadata.SecretID(), string(secretValue), s.Metadata.Map(), overwrite, log)
 }
 
 // FromCSVRecord converts a CSV record to a valid Secret
 func FromCSVRecord(record Record, log *zerolog.Logger) (secret Secret, err error) {
+	replicaRegions, err := tools.ParseReplicaRegions(record.ReplicaRegions)
+	if err != nil {
+		log.Error().Err(err).Msg("error parsing replica regions")
+		return secret, err
+	}
+	kmsKeyID, err := tools.ParseKMSKeyID(record.KmsKeyID)
+	if err != nil {
+		log.Error().Err(err).Msg("error parsing KMS key")
+		return secret, err
+	}
 
 	secret = Secret{
+		Options: store.Options{ReplicaRegions: replicaRegions, KmsKeyID: kmsKeyID},
 		Data: Data{
 			Password:    record.Password,
 			AccountName: record.AccountName,
@@ -194,3 +158,25 @@ func FromCSVRecord(record Record, log *zerolog.Logger) (secret Secret, err error
 	return secret, err
 
 }
+
+// RecordFromSecret converts a stored secret back to a Record. tags are the tags of the secret
+// and value its JSON value. dir isn't used: snowflake records have no files
+func RecordFromSecret(tags map[string]string, value, dir string) (record Record, err error) {
+	if err = tools.RequireTags(tags, "ResourceType", "Environment", "Warehouse", "Access"); err != nil {
+		return record, err
+	}
+	var data Data
+	if err = json.Unmarshal([]byte(value), &data); err != nil {
+		return record, fmt.Errorf("error unmarshalling secret value: %w", err)
+	}
+	record = Record{
+		ResourceType: tags["ResourceType"],
+		Environment:  tags["Environment"],
+		Warehouse:    tags["Warehouse"],
+		Access:       tags["Access"],
+		AccountName:  data.AccountName,
+		Username:     data.Username,
+		Password:     data.Password,
+	}
+	return record, nil
+}
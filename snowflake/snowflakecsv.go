@@ -1,7 +1,8 @@
 package snowflake
 
 import (
-	"strings"
+	"errors"
+	"fmt"
 
 	"github.com/natemarks/secret-hoard/tools"
 	"github.com/rs/zerolog"
@@ -9,14 +10,37 @@ import (
 
 // Record is the struct of the snowflake record
 type Record struct {
-	ResourceType string `json:"resourceType"` // record[0] : rdspostgres
-	Environment  string `json:"environment"`  // record[1] : dev, integration, staging, production
-	Warehouse    string `json:"warehouse"`    // record[2] : snowflake warehouse
-	Access       string `json:"access"`       // record[3] : app_readwrite, app_readonly, etc.
-	AccountName  string `json:"accountName"`  // record[4] : snowflake account name
-	Username     string `json:"username"`     // record[5] : username
-	Password     string `json:"password"`     // record[6] : password
+	ResourceType   string `json:"resourceType"`             // rdspostgres
+	Environment    string `json:"environment"`              // dev, integration, staging, production
+	Warehouse      string `json:"warehouse"`                // snowflake warehouse
+	Access         string `json:"access"`                   // app_readwrite, app_readonly, etc.
+	AccountName    string `json:"accountName"`              // snowflake account name
+	Username       string `json:"username"`                 // username
+	Password       string `json:"password"`                 // password
+	ReplicaRegions string `json:"replicaRegions,omitempty"` // replica regions separated by ; or spaces ex. us-west-2;eu-west-1
+	KmsKeyID       string `json:"kmsKeyId,omitempty"`       // KMS key ARN to encrypt the secret with
+	Row            int    `json:"-"`                        // CSV row number, 0 when the record isn't read from a CSV file
+}
+
+// String formats the record for logs with the password masked
+func (scr Record) String() string {
+	scr.Password = tools.Mask(scr.Password)
+	type plain Record // plain has no String method
+	return fmt.Sprintf("%+v", plain(scr))
+}
 
+// MarshalZerologObject logs the record with the password masked
+func (scr Record) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("resourceType", scr.ResourceType).
+		Str("environment", scr.Environment).
+		Str("warehouse", scr.Warehouse).
+		Str("access", scr.Access).
+		Str("accountName", scr.AccountName).
+		Str("username", scr.Username).
+		Str("password", tools.Mask(scr.Password)).
+		Str("replicaRegions", scr.ReplicaRegions).
+		Str("kmsKeyId", scr.KmsKeyID).
+		Int("row", scr.Row)
 }
 
 // CSVColumns Usage output describing the CSV structure
@@ -26,30 +50,62 @@ func (scr Record) CSVColumns() string {
 	return result
 }
 
-// RecordsFromCSV reads a CSV file and returns a slice of Record
+// Columns are the columns of the CSV file. The header must name every column in any order
+var Columns = []tools.CSVColumn{
+	{Name: "ResourceType"},
+	{Name: "Environment"},
+	{Name: "Warehouse"},
+	{Name: "Access"},
+	{Name: "AccountName"},
+	{Name: "Username"},
+	{Name: "Password"},
+	{Name: "ReplicaRegions", Optional: true},
+	{Name: "KmsKeyId", Optional: true},
+}
+
+// CSVValues returns the values of the record in the order of Columns
+func (scr Record) CSVValues() []string {
+	return []string{scr.ResourceType, scr.Environment, scr.Warehouse, scr.Access, scr.AccountName, scr.Username, scr.Password, scr.ReplicaRegions, scr.KmsKeyID}
+}
+
+// RecordFromCSVRow converts a CSV row read with Columns to a Record
+func RecordFromCSVRow(row tools.CSVRow) (record Record, err error) {
+	record = Record{
+		ResourceType:   row.Get("ResourceType"),
+		Environment:    row.Get("Environment"),
+		Warehouse:      row.Get("Warehouse"),
+		Access:         row.Get("Access"),
+		AccountName:    row.Get("AccountName"),
+		Username:       row.Get("Username"),
+		Password:       row.Get("Password"),
+		ReplicaRegions: row.Get("ReplicaRegions"),
+		KmsKeyID:       row.Get("KmsKeyId"),
+		Row:            row.Row,
+	}
+	return record, nil
+}
+
+// RecordsFromCSV reads a CSV file and returns a slice of Records. The columns are matched by
+// the header row. It returns an error for missing or unknown columns and invalid rows
 func RecordsFromCSV(csvFile string, log *zerolog.Logger) (result []Record, err error) {
-	records, err := tools.GetCSVRecordsFromFile(csvFile)
+	rows, err := tools.GetCSVRowsFromFile(csvFile, Columns)
 	if err != nil {
-		log.Error().Err(err).Msg("error reading store contents string")
+		log.Error().Err(err).Msg("error reading CSV file")
 		return result, err
 	}
 
-	for _, record := range records {
-		// skip the header row
-		if strings.ToLower(record[0]) == "resourcetype" {
+	var errs []error
+	for _, row := range rows {
+		record, err := RecordFromCSVRow(row)
+		if err != nil {
+			errs = append(errs, row.Errorf("%w", err))
 			continue
 		}
-
-		result = append(result, Record{
-			ResourceType: record[0],
-			Environment:  record[1],
-			Warehouse:    record[2],
-			Access:       record[3],
-			AccountName:  record[4],
-			Username:     record[5],
-			Password:     record[6],
-		})
-
+		result = append(result, record)
+	}
+	if err = errors.Join(errs...); err != nil {
+		log.Error().Err(err).Msgf("invalid rows in CSV file: %s", csvFile)
+		return nil, err
 	}
 	return result, nil
 }
@@ -4,9 +4,9 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/natemarks/secret-hoard/tools"
@@ -15,11 +15,32 @@ import (
 
 // Record is the struct of the SSL certificate record
 type Record struct {
-	ResourceType    string `json:"resourceType"`    // record[0] : ssl_certificate
-	Environment     string `json:"environment"`     // record[1] : dev, integration, staging, production
-	CommonName      string `json:"commonName"`      // record[2] : \*.my.domain.com | server.my.domain.com
-	CertificateFile string `json:"certificateFile"` // record[3] : /path/to/certificate.crt
-	PrivateKeyFile  string `json:"privateKeyFile"`  // record[4] : /path/to/private.key
+	ResourceType    string `json:"resourceType"`             // ssl_certificate
+	Environment     string `json:"environment"`              // dev, integration, staging, production
+	CommonName      string `json:"commonName"`               // \*.my.domain.com | server.my.domain.com
+	CertificateFile string `json:"certificateFile"`          // /path/to/certificate.crt
+	PrivateKeyFile  string `json:"privateKeyFile"`           // /path/to/private.key
+	ReplicaRegions  string `json:"replicaRegions,omitempty"` // replica regions separated by ; or spaces ex. us-west-2;eu-west-1
+	KmsKeyID        string `json:"kmsKeyId,omitempty"`       // KMS key ARN to encrypt the secret with
+	Row             int    `json:"-"`                        // CSV row number, 0 when the record isn't read from a CSV file
+}
+
+// String formats the record for logs. Records only hold file paths, not secret values
+func (scr Record) String() string {
+	type plain Record // plain has no String method
+	return fmt.Sprintf("%+v", plain(scr))
+}
+
+// MarshalZerologObject logs the record
+func (scr Record) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("resourceType", scr.ResourceType).
+		Str("environment", scr.Environment).
+		Str("commonName", scr.CommonName).
+		Str("certificateFile", scr.CertificateFile).
+		Str("privateKeyFile", scr.PrivateKeyFile).
+		Str("replicaRegions", scr.ReplicaRegions).
+		Str("kmsKeyId", scr.KmsKeyID).
+		Int("row", scr.Row)
 }
 
 // CSVColumns Usage output describing the CSV structure
@@ -130,27 +151,58 @@ func (scr Record) PrivateKeyContents() (contents string, err error) {
 	return contents, err
 }
 
-// RecordsFromCSV reads a CSV file and returns a slice of SSLCertRecords
+// Columns are the columns of the CSV file. The header must name every column in any order
+var Columns = []tools.CSVColumn{
+	{Name: "ResourceType"},
+	{Name: "Environment"},
+	{Name: "CommonName"},
+	{Name: "CertificateFile"},
+	{Name: "PrivateKeyFile"},
+	{Name: "ReplicaRegions", Optional: true},
+	{Name: "KmsKeyId", Optional: true},
+}
+
+// CSVValues returns the values of the record in the order of Columns
+func (scr Record) CSVValues() []string {
+	return []string{scr.ResourceType, scr.Environment, scr.CommonName, scr.CertificateFile, scr.PrivateKeyFile, scr.ReplicaRegions, scr.KmsKeyID}
+}
+
+// RecordFromCSVRow converts a CSV row read with Columns to a Record
+func RecordFromCSVRow(row tools.CSVRow) (record Record, err error) {
+	record = Record{
+		ResourceType:    row.Get("ResourceType"),
+		Environment:     row.Get("Environment"),
+		CommonName:      row.Get("CommonName"),
+		CertificateFile: row.Get("CertificateFile"),
+		PrivateKeyFile:  row.Get("PrivateKeyFile"),
+		ReplicaRegions:  row.Get("ReplicaRegions"),
+		KmsKeyID:        row.Get("KmsKeyId"),
+		Row:             row.Row,
+	}
+	return record, nil
+}
+
+// RecordsFromCSV reads a CSV file and returns a slice of Records. The columns are matched by
+// the header row. It returns an error for missing or unknown columns and invalid rows
 func RecordsFromCSV(csvFile string, log *zerolog.Logger) (result []Record, err error) {
-	records, err := tools.GetCSVRecordsFromFile(csvFile)
+	rows, err := tools.GetCSVRowsFromFile(csvFile, Columns)
 	if err != nil {
-		log.Error().Err(err).Msg("error reading store contents string")
+		log.Error().Err(err).Msg("error reading CSV file")
 		return result, err
 	}
 
-	for _, record := range records {
-		// skip the header row
-		if strings.ToLower(record[0]) == "resourcetype" {
+	var errs []error
+	for _, row := range rows {
+		record, err := RecordFromCSVRow(row)
+		if err != nil {
+			errs = append(errs, row.Errorf("%w", err))
 			continue
 		}
-		result = append(result, Record{
-			ResourceType:    record[0],
-			Environment:     record[1],
-			CommonName:      record[2],
-			CertificateFile: record[3],
-			PrivateKeyFile:  record[4],
-		})
-
+		result = append(result, record)
+	}
+	if err = errors.Join(errs...); err != nil {
+		log.Error().Err(err).Msgf("invalid rows in CSV file: %s", csvFile)
+		return nil, err
 	}
 	return result, nil
 }
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore implements SecretStore with a JSON file. Every call loads the file into a
+// MemoryStore, applies the operation and writes the file back if anything changed.
+// Use it to keep fake secrets between runs of the secret-hoard commands
+type FileStore struct {
+	Path string
+	mu   sync.Mutex
+}
+
+// NewFileStore returns a FileStore backed by path. The file is created on the first write
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+// load reads the file into a MemoryStore. A missing file is an empty store
+func (f *FileStore) load() (*MemoryStore, error) {
+	m := NewMemoryStore()
+	content, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(content, &m.secrets); err != nil {
+		return nil, fmt.Errorf("invalid secret store file %s: %w", f.Path, err)
+	}
+	if m.secrets == nil {
+		m.secrets = map[string]*memorySecret{}
+	}
+	return m, nil
+}
+
+// save writes the MemoryStore to a temporary file and renames it over the store file
+func (f *FileStore) save(m *MemoryStore) error {
+	content, err := json.MarshalIndent(m.secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), filepath.Base(f.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// view runs fn against the stored secrets without saving
+func (f *FileStore) view(fn func(m *MemoryStore) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := f.load()
+	if err != nil {
+		return err
+	}
+	return fn(m)
+}
+
+// update runs fn against the stored secrets and saves them if fn succeeds
+func (f *FileStore) update(fn func(m *MemoryStore) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, err := f.load()
+	if err != nil {
+		return err
+	}
+	if err = fn(m); err != nil {
+		return err
+	}
+	return f.save(m)
+}
+
+// Describe returns the description of a secret
+func (f *FileStore) Describe(ctx context.Context, secretID string) (result Description, err error) {
+	err = f.view(func(m *MemoryStore) error {
+		result, err = m.Describe(ctx, secretID)
+		return err
+	})
+	return result, err
+}
+
+// Get returns the AWSCURRENT value of a secret
+func (f *FileStore) Get(ctx context.Context, secretID string) (result string, err error) {
+	err = f.view(func(m *MemoryStore) error {
+		result, err = m.Get(ctx, secretID)
+		return err
+	})
+	return result, err
+}
+
+// GetVersion returns the value of the version with versionID or stage
+func (f *FileStore) GetVersion(ctx context.Context, secretID, versionID, stage string) (result string, err error) {
+	err = f.view(func(m *MemoryStore) error {
+		result, err = m.GetVersion(ctx, secretID, versionID, stage)
+		return err
+	})
+	return result, err
+}
+
+// Create creates a new secret
+func (f *FileStore) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (versionID string, err error) {
+	err = f.update(func(m *MemoryStore) error {
+		versionID, err = m.Create(ctx, secretID, value, tags, kmsKeyID)
+		return err
+	})
+	return versionID, err
+}
+
+// Put stores a new AWSCURRENT value
+func (f *FileStore) Put(ctx context.Context, secretID, value string) (versionID string, err error) {
+	err = f.update(func(m *MemoryStore) error {
+		versionID, err = m.Put(ctx, secretID, value)
+		return err
+	})
+	return versionID, err
+}
+
+// Tag adds or overwrites tags
+func (f *FileStore) Tag(ctx context.Context, secretID string, tags map[string]string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.Tag(ctx, secretID, tags)
+	})
+}
+
+// Delete removes a secret immediately
+func (f *FileStore) Delete(ctx context.Context, secretID string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.Delete(ctx, secretID)
+	})
+}
+
+// ScheduleDeletion sets the deletion date of a secret
+func (f *FileStore) ScheduleDeletion(ctx context.Context, secretID string, recoveryWindowDays int) (deletionDate time.Time, err error) {
+	err = f.update(func(m *MemoryStore) error {
+		deletionDate, err = m.ScheduleDeletion(ctx, secretID, recoveryWindowDays)
+		return err
+	})
+	return deletionDate, err
+}
+
+// Restore cancels the scheduled deletion of a secret
+func (f *FileStore) Restore(ctx context.Context, secretID string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.Restore(ctx, secretID)
+	})
+}
+
+// ReplicateRegions adds replica regions to a secret
+func (f *FileStore) ReplicateRegions(ctx context.Context, secretID string, regions []string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.ReplicateRegions(ctx, secretID, regions)
+	})
+}
+
+// RemoveReplicaRegions removes replica regions from a secret
+func (f *FileStore) RemoveReplicaRegions(ctx context.Context, secretID string, regions []string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.RemoveReplicaRegions(ctx, secretID, regions)
+	})
+}
+
+// SetKMSKey records the KMS key of a secret
+func (f *FileStore) SetKMSKey(ctx context.Context, secretID, kmsKeyID string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.SetKMSKey(ctx, secretID, kmsKeyID)
+	})
+}
+
+// List returns the secrets matching tagFilters sorted by name
+func (f *FileStore) List(ctx context.Context, tagFilters map[string]string) (result []Description, err error) {
+	err = f.view(func(m *MemoryStore) error {
+		result, err = m.List(ctx, tagFilters)
+		return err
+	})
+	return result, err
+}
+
+// Versions returns the versions of a secret sorted by creation date
+func (f *FileStore) Versions(ctx context.Context, secretID string) (result []Version, err error) {
+	err = f.view(func(m *MemoryStore) error {
+		result, err = m.Versions(ctx, secretID)
+		return err
+	})
+	return result, err
+}
+
+// UpdateVersionStage moves a version stage between versions
+func (f *FileStore) UpdateVersionStage(ctx context.Context, secretID, stage, moveToVersionID, removeFromVersionID string) error {
+	return f.update(func(m *MemoryStore) error {
+		return m.UpdateVersionStage(ctx, secretID, stage, moveToVersionID, removeFromVersionID)
+	})
+}
@@ -0,0 +1,23 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	testSecretStore(t, NewFileStore(filepath.Join(t.TempDir(), "secrets.json")))
+}
+
+func TestFileStorePersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	if _, err := NewFileStore(path).Create(ctx, "jsondoc/testenv/access", "value", nil, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	value, err := NewFileStore(path).Get(ctx, "jsondoc/testenv/access")
+	if err != nil || value != "value" {
+		t.Fatalf("Get() = %q, %v, want \"value\"", value, err)
+	}
+}
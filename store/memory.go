@@ -0,0 +1,452 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryVersion is one version of a secret value
+type memoryVersion struct {
+	Value       string    `json:"value"`
+	Stages      []string  `json:"stages"`
+	CreatedDate time.Time `json:"createdDate"`
+}
+
+// memorySecret is the stored state of a secret
+type memorySecret struct {
+	Name            string                   `json:"name"`
+	Tags            map[string]string        `json:"tags"`
+	Versions        map[string]memoryVersion `json:"versions"` // version ID -> version
+	LastChangedDate time.Time                `json:"lastChangedDate"`
+	DeletedDate     *time.Time               `json:"deletedDate,omitempty"`
+	ReplicaRegions  []string                 `json:"replicaRegions,omitempty"` // sorted
+	KmsKeyID        string                   `json:"kmsKeyId,omitempty"`
+}
+
+// MemoryStore implements SecretStore in memory. Version stages are moved the same way
+// Secrets Manager moves them: a new value becomes AWSCURRENT and the old AWSCURRENT
+// version becomes AWSPREVIOUS
+type MemoryStore struct {
+	mu      sync.Mutex
+	secrets map[string]*memorySecret
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{secrets: map[string]*memorySecret{}}
+}
+
+// newVersionID returns a random version ID formatted like a UUID
+func newVersionID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	h := hex.EncodeToString(b)
+	return fmt.Sprintf("%s-%s-%s-%s-%s", h[0:8], h[8:12], h[12:16], h[16:20], h[20:32])
+}
+
+// copyTags returns a copy of tags that is never nil
+func copyTags(tags map[string]string) map[string]string {
+	result := make(map[string]string, len(tags))
+	for key, value := range tags {
+		result[key] = value
+	}
+	return result
+}
+
+// hasStage returns true if stages contains stage
+func hasStage(stages []string, stage string) bool {
+	for _, s := range stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
+
+// removeStage returns stages without stage
+func removeStage(stages []string, stage string) []string {
+	var result []string
+	for _, s := range stages {
+		if s != stage {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// lookup returns the secret or a wrapped ErrNotFound
+func (m *MemoryStore) lookup(secretID string) (*memorySecret, error) {
+	secret, ok := m.secrets[secretID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, secretID)
+	}
+	return secret, nil
+}
+
+// lookupActive returns the secret or a wrapped ErrNotFound. Like Secrets Manager it returns a
+// wrapped ErrInvalidRequest and ErrScheduledForDeletion for secrets scheduled for deletion
+func (m *MemoryStore) lookupActive(secretID string) (*memorySecret, error) {
+	secret, err := m.lookup(secretID)
+	if err != nil {
+		return nil, err
+	}
+	if secret.DeletedDate != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRequest, scheduledForDeletion(secretID, secret.describe()))
+	}
+	return secret, nil
+}
+
+// describe builds the Description of a secret
+func (secret *memorySecret) describe() Description {
+	lastChanged := secret.LastChangedDate
+	stages := make(map[string][]string, len(secret.Versions))
+	for versionID, version := range secret.Versions {
+		if len(version.Stages) > 0 {
+			stages[versionID] = append([]string(nil), version.Stages...)
+		}
+	}
+	var replicas []Replica
+	for _, region := range secret.ReplicaRegions {
+		replicas = append(replicas, Replica{Region: region, Status: "InSync"})
+	}
+	return Description{
+		Name:            secret.Name,
+		ARN:             "arn:aws:secretsmanager:local:000000000000:secret:" + secret.Name,
+		Tags:            copyTags(secret.Tags),
+		LastChangedDate: &lastChanged,
+		DeletedDate:     secret.DeletedDate,
+		VersionStages:   stages,
+		Replicas:        replicas,
+		KmsKeyID:        secret.KmsKeyID,
+	}
+}
+
+// currentVersionID returns the ID of the AWSCURRENT version
+func (secret *memorySecret) currentVersionID() (string, bool) {
+	versionID := secret.stageHolder(StageCurrent)
+	return versionID, versionID != ""
+}
+
+// put adds a new AWSCURRENT version and moves AWSPREVIOUS to the old AWSCURRENT version.
+// An empty versionID is generated
+func (secret *memorySecret) put(versionID, value string, now time.Time) string {
+	oldCurrent, _ := secret.currentVersionID()
+	for versionID, version := range secret.Versions {
+		version.Stages = removeStage(version.Stages, StagePrevious)
+		if versionID == oldCurrent {
+			version.Stages = append(removeStage(version.Stages, StageCurrent), StagePrevious)
+		}
+		secret.Versions[versionID] = version
+	}
+	if versionID == "" {
+		versionID = newVersionID()
+	}
+	secret.Versions[versionID] = memoryVersion{
+		Value:       value,
+		Stages:      []string{StageCurrent},
+		CreatedDate: now,
+	}
+	secret.LastChangedDate = now
+	return versionID
+}
+
+// stageHolder returns the ID of the version that has stage
+func (secret *memorySecret) stageHolder(stage string) string {
+	for versionID, version := range secret.Versions {
+		if hasStage(version.Stages, stage) {
+			return versionID
+		}
+	}
+	return ""
+}
+
+// Describe returns the description of a secret
+func (m *MemoryStore) Describe(_ context.Context, secretID string) (Description, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookup(secretID)
+	if err != nil {
+		return Description{}, err
+	}
+	return secret.describe(), nil
+}
+
+// Get returns the AWSCURRENT value of a secret
+func (m *MemoryStore) Get(ctx context.Context, secretID string) (string, error) {
+	return m.GetVersion(ctx, secretID, "", "")
+}
+
+// GetVersion returns the value of the version with versionID or stage
+func (m *MemoryStore) GetVersion(_ context.Context, secretID, versionID, stage string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return "", err
+	}
+	if versionID == "" && stage == "" {
+		stage = StageCurrent
+	}
+	if versionID == "" {
+		if versionID = secret.stageHolder(stage); versionID == "" {
+			return "", fmt.Errorf("%w: secret has no %s version: %s", ErrNotFound, stage, secretID)
+		}
+	}
+	version, ok := secret.Versions[versionID]
+	if !ok {
+		return "", fmt.Errorf("%w: version %s of %s", ErrNotFound, versionID, secretID)
+	}
+	if stage != "" && !hasStage(version.Stages, stage) {
+		return "", fmt.Errorf("%w: version %s of %s doesn't have stage %s", ErrInvalidRequest, versionID, secretID, stage)
+	}
+	return version.Value, nil
+}
+
+// Create creates a new secret. The KMS key is only recorded. The ClientRequestToken of ctx
+// is the version ID
+func (m *MemoryStore) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.secrets[secretID]; ok {
+		if existing.DeletedDate != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidRequest, scheduledForDeletion(secretID, existing.describe()))
+		}
+		return "", fmt.Errorf("%w: %s", ErrExists, secretID)
+	}
+	secret := &memorySecret{
+		Name:     secretID,
+		Tags:     copyTags(tags),
+		Versions: map[string]memoryVersion{},
+		KmsKeyID: kmsKeyID,
+	}
+	versionID := secret.put(ClientRequestToken(ctx), value, time.Now().UTC())
+	m.secrets[secretID] = secret
+	return versionID, nil
+}
+
+// Put stores a new AWSCURRENT value. Like Secrets Manager a repeated ClientRequestToken of
+// ctx with the same value is ignored and returns the version it created
+func (m *MemoryStore) Put(ctx context.Context, secretID, value string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return "", err
+	}
+	token := ClientRequestToken(ctx)
+	if version, ok := secret.Versions[token]; ok {
+		if version.Value != value {
+			return "", fmt.Errorf("%w: version %s of %s has another value", ErrInvalidRequest, token, secretID)
+		}
+		return token, nil
+	}
+	return secret.put(token, value, time.Now().UTC()), nil
+}
+
+// Tag adds or overwrites tags
+func (m *MemoryStore) Tag(_ context.Context, secretID string, tags map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return err
+	}
+	for key, value := range tags {
+		secret.Tags[key] = value
+	}
+	secret.LastChangedDate = time.Now().UTC()
+	return nil
+}
+
+// Delete removes a secret immediately
+func (m *MemoryStore) Delete(_ context.Context, secretID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, err := m.lookup(secretID); err != nil {
+		return err
+	}
+	delete(m.secrets, secretID)
+	return nil
+}
+
+// ScheduleDeletion sets the deletion date of a secret. The secret stays in the store
+// until it's deleted with Delete
+func (m *MemoryStore) ScheduleDeletion(_ context.Context, secretID string, recoveryWindowDays int) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := checkRecoveryWindow(recoveryWindowDays); err != nil {
+		return time.Time{}, err
+	}
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := time.Now().UTC()
+	deletionDate := now.AddDate(0, 0, recoveryWindowDays)
+	secret.DeletedDate = &deletionDate
+	secret.LastChangedDate = now
+	return deletionDate, nil
+}
+
+// Restore cancels the scheduled deletion of a secret. Restoring a secret that isn't scheduled
+// for deletion does nothing
+func (m *MemoryStore) Restore(_ context.Context, secretID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookup(secretID)
+	if err != nil {
+		return err
+	}
+	if secret.DeletedDate != nil {
+		secret.DeletedDate = nil
+		secret.LastChangedDate = time.Now().UTC()
+	}
+	return nil
+}
+
+// List returns the secrets matching tagFilters sorted by name
+func (m *MemoryStore) List(_ context.Context, tagFilters map[string]string) ([]Description, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []Description
+	for _, secret := range m.secrets {
+		if secret.DeletedDate != nil || !MatchesTags(secret.Tags, tagFilters) {
+			continue
+		}
+		result = append(result, secret.describe())
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// Versions returns the versions of a secret sorted by creation date
+func (m *MemoryStore) Versions(_ context.Context, secretID string) ([]Version, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookup(secretID)
+	if err != nil {
+		return nil, err
+	}
+	var result []Version
+	for versionID, version := range secret.Versions {
+		created := version.CreatedDate
+		result = append(result, Version{
+			VersionID:   versionID,
+			Stages:      append([]string(nil), version.Stages...),
+			CreatedDate: &created,
+		})
+	}
+	SortVersions(result)
+	return result, nil
+}
+
+// UpdateVersionStage moves a version stage between versions with the same rules as
+// Secrets Manager: a stage attached to another version can only be moved if that version
+// is given as removeFromVersionID
+func (m *MemoryStore) UpdateVersionStage(_ context.Context, secretID, stage, moveToVersionID, removeFromVersionID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return err
+	}
+	for _, versionID := range []string{moveToVersionID, removeFromVersionID} {
+		if _, ok := secret.Versions[versionID]; versionID != "" && !ok {
+			return fmt.Errorf("%w: version %s of %s", ErrNotFound, versionID, secretID)
+		}
+	}
+	holder := secret.stageHolder(stage)
+	if removeFromVersionID != "" && removeFromVersionID != holder {
+		return fmt.Errorf("%w: %s is not attached to version %s", ErrInvalidRequest, stage, removeFromVersionID)
+	}
+	if moveToVersionID != "" && holder != "" && holder != moveToVersionID && removeFromVersionID != holder {
+		return fmt.Errorf("%w: %s is attached to version %s, it must be removed from it", ErrInvalidRequest, stage, holder)
+	}
+	if removeFromVersionID != "" {
+		version := secret.Versions[removeFromVersionID]
+		version.Stages = removeStage(version.Stages, stage)
+		secret.Versions[removeFromVersionID] = version
+	}
+	if moveToVersionID != "" && moveToVersionID != holder {
+		version := secret.Versions[moveToVersionID]
+		version.Stages = append(version.Stages, stage)
+		secret.Versions[moveToVersionID] = version
+		// the old AWSCURRENT version becomes AWSPREVIOUS
+		if stage == StageCurrent && holder != "" {
+			for versionID, version := range secret.Versions {
+				version.Stages = removeStage(version.Stages, StagePrevious)
+				if versionID == holder {
+					version.Stages = append(version.Stages, StagePrevious)
+				}
+				secret.Versions[versionID] = version
+			}
+		}
+	}
+	secret.LastChangedDate = time.Now().UTC()
+	return nil
+}
+
+// ReplicateRegions adds the regions to the replica regions of a secret. Replicas are always
+// in sync because the values aren't copied
+func (m *MemoryStore) ReplicateRegions(_ context.Context, secretID string, regions []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return err
+	}
+	for _, region := range regions {
+		if region == "" {
+			return fmt.Errorf("%w: empty replica region: %s", ErrInvalidRequest, secretID)
+		}
+		if !slices.Contains(secret.ReplicaRegions, region) {
+			secret.ReplicaRegions = append(secret.ReplicaRegions, region)
+		}
+	}
+	sort.Strings(secret.ReplicaRegions)
+	secret.LastChangedDate = time.Now().UTC()
+	return nil
+}
+
+// RemoveReplicaRegions removes the regions from the replica regions of a secret. Like Secrets
+// Manager it returns a wrapped ErrInvalidRequest for regions without a replica
+func (m *MemoryStore) RemoveReplicaRegions(_ context.Context, secretID string, regions []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return err
+	}
+	for _, region := range regions {
+		if !slices.Contains(secret.ReplicaRegions, region) {
+			return fmt.Errorf("%w: %s isn't replicated to %s", ErrInvalidRequest, secretID, region)
+		}
+	}
+	secret.ReplicaRegions = slices.DeleteFunc(secret.ReplicaRegions, func(region string) bool {
+		return slices.Contains(regions, region)
+	})
+	secret.LastChangedDate = time.Now().UTC()
+	return nil
+}
+
+// SetKMSKey records the KMS key of a secret
+func (m *MemoryStore) SetKMSKey(_ context.Context, secretID, kmsKeyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	secret, err := m.lookupActive(secretID)
+	if err != nil {
+		return err
+	}
+	if kmsKeyID == "" {
+		return fmt.Errorf("%w: empty KMS key ID: %s", ErrInvalidRequest, secretID)
+	}
+	secret.KmsKeyID = kmsKeyID
+	secret.LastChangedDate = time.Now().UTC()
+	return nil
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// stagesOf returns the stages of versionID
+func stagesOf(t *testing.T, st SecretStore, secretID, versionID string) []string {
+	t.Helper()
+	description, err := st.Describe(context.Background(), secretID)
+	if err != nil {
+		t.Fatalf("Describe() error = %v", err)
+	}
+	return description.VersionStages[versionID]
+}
+
+// testSecretStore runs the same round trip against any SecretStore
+func testSecretStore(t *testing.T, st SecretStore) {
+	ctx := context.Background()
+	secretID := "rdspostgres/testenv/myinstance/mydb/mytype"
+	tags := map[string]string{"ResourceType": "rdspostgres", "Environment": "testenv", "Source": "secret-hoard"}
+
+	if _, err := st.Describe(ctx, secretID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Describe() error = %v, want ErrNotFound", err)
+	}
+	first, err := st.Create(ctx, secretID, "one", tags, "")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if _, err = st.Create(ctx, secretID, "one", tags, ""); !errors.Is(err, ErrExists) {
+		t.Fatalf("Create() error = %v, want ErrExists", err)
+	}
+	second, err := st.Put(ctx, secretID, "two")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	value, err := st.Get(ctx, secretID)
+	if err != nil || value != "two" {
+		t.Fatalf("Get() = %q, %v, want \"two\"", value, err)
+	}
+	if got := stagesOf(t, st, secretID, second); !reflect.DeepEqual(got, []string{StageCurrent}) {
+		t.Errorf("second version stages = %v", got)
+	}
+	if got := stagesOf(t, st, secretID, first); !reflect.DeepEqual(got, []string{StagePrevious}) {
+		t.Errorf("first version stages = %v", got)
+	}
+
+	third, err := st.Put(ctx, secretID, "three")
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if got := stagesOf(t, st, secretID, first); len(got) != 0 {
+		t.Errorf("first version stages = %v, want none", got)
+	}
+	if got := stagesOf(t, st, secretID, second); !reflect.DeepEqual(got, []string{StagePrevious}) {
+		t.Errorf("second version stages = %v", got)
+	}
+	if got := stagesOf(t, st, secretID, third); !reflect.DeepEqual(got, []string{StageCurrent}) {
+		t.Errorf("third version stages = %v", got)
+	}
+
+	if err = st.Tag(ctx, secretID, map[string]string{"Access": "mytype"}); err != nil {
+		t.Fatalf("Tag() error = %v", err)
+	}
+	listed, err := st.List(ctx, map[string]string{"Source": "secret-hoard", "Access": "mytype"})
+	if err != nil || len(listed) != 1 || listed[0].Name != secretID {
+		t.Fatalf("List() = %v, %v", listed, err)
+	}
+	listed, err = st.List(ctx, map[string]string{"Environment": "prod"})
+	if err != nil || len(listed) != 0 {
+		t.Fatalf("List() = %v, %v, want none", listed, err)
+	}
+
+	// restoring a secret that isn't scheduled for deletion does nothing
+	if err = st.Restore(ctx, secretID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if _, err = st.ScheduleDeletion(ctx, secretID, DefaultRecoveryWindowDays); err != nil {
+		t.Fatalf("ScheduleDeletion() error = %v", err)
+	}
+	if description, err := st.Describe(ctx, secretID); err != nil || !description.ScheduledForDeletion() {
+		t.Fatalf("Describe() = %+v, %v, want scheduled for deletion", description, err)
+	}
+	if err = st.Restore(ctx, secretID); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	if err = st.Delete(ctx, secretID); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err = st.Get(ctx, secretID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want ErrNotFound", err)
+	}
+	if err = st.Restore(ctx, secretID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Restore() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testSecretStore(t, NewMemoryStore())
+}
+
+func TestMemoryStoreScheduledForDeletion(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	if _, err := st.Create(ctx, "one", "value", map[string]string{"Source": "secret-hoard"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.ScheduleDeletion(ctx, "one", MinRecoveryWindowDays-1); !errors.Is(err, ErrInvalidRequest) {
+		t.Fatalf("ScheduleDeletion(6) error = %v, want ErrInvalidRequest", err)
+	}
+	deletionDate, err := st.ScheduleDeletion(ctx, "one", MinRecoveryWindowDays)
+	if err != nil {
+		t.Fatalf("ScheduleDeletion() error = %v", err)
+	}
+	if wait := time.Until(deletionDate); wait < 6*24*time.Hour || wait > 7*24*time.Hour {
+		t.Errorf("ScheduleDeletion() = %v, want in 7 days", deletionDate)
+	}
+	if _, err = st.ScheduleDeletion(ctx, "one", MinRecoveryWindowDays); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("ScheduleDeletion() error = %v, want ErrInvalidRequest when already scheduled", err)
+	}
+
+	if description, err := st.Describe(ctx, "one"); err != nil || !description.ScheduledForDeletion() {
+		t.Fatalf("Describe() = %+v, %v, want scheduled for deletion", description, err)
+	}
+	if _, err := st.Get(ctx, "one"); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Get() error = %v, want ErrInvalidRequest", err)
+	}
+	if _, err := st.Put(ctx, "one", "two"); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Put() error = %v, want ErrInvalidRequest", err)
+	}
+	if _, err := st.Create(ctx, "one", "two", nil, ""); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("Create() error = %v, want ErrInvalidRequest", err)
+	}
+	if listed, err := st.List(ctx, nil); err != nil || len(listed) != 0 {
+		t.Errorf("List() = %v, %v, want none", listed, err)
+	}
+
+	if err := st.Restore(ctx, "one"); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if value, err := st.Get(ctx, "one"); err != nil || value != "value" {
+		t.Errorf("Get() = %q, %v after restore", value, err)
+	}
+}
@@ -0,0 +1,157 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Action is what writing a secret does or would do
+type Action string
+
+// Actions reported for every secret
+const (
+	ActionCreate    Action = "create"    // the secret doesn't exist
+	ActionUpdate    Action = "update"    // the stored value or tags differ
+	ActionUnchanged Action = "unchanged" // the stored value and tags match
+	ActionSkip      Action = "skip"      // the secret differs but overwrite is false
+	ActionRestore   Action = "restore"   // the secret is scheduled for deletion and would be restored. only planned
+	ActionDelete    Action = "delete"    // the secret isn't in the file and is scheduled for deletion by -prune
+)
+
+// Change describes the difference between a secret and what is stored
+type Change struct {
+	SecretID string
+	Action   Action
+	Fields   []string          // changed value fields prefixed with +, ~ or -
+	Tags     map[string]string // tags that would be added or changed
+	Replicas []string          // replica regions that would be added or removed prefixed with + or -
+	KmsKeyID string            // KMS key the secret would be encrypted with when it differs
+	// VersionID is the AWSCURRENT version of the stored secret
+	VersionID string
+}
+
+// CurrentVersionID returns the version ID with the AWSCURRENT stage
+func (d Description) CurrentVersionID() string {
+	return d.StageVersionID(StageCurrent)
+}
+
+// StageVersionID returns the version ID with stage. An empty stage is AWSCURRENT
+func (d Description) StageVersionID(stage string) string {
+	if stage == "" {
+		stage = StageCurrent
+	}
+	for versionID, stages := range d.VersionStages {
+		if hasStage(stages, stage) {
+			return versionID
+		}
+	}
+	return ""
+}
+
+// fieldChanges compares two JSON values field by field. Values that aren't JSON objects
+// are compared as a single "value" field
+func fieldChanges(stored, value string) []string {
+	var oldFields, newFields map[string]any
+	if json.Unmarshal([]byte(stored), &oldFields) != nil || json.Unmarshal([]byte(value), &newFields) != nil {
+		if stored == value {
+			return nil
+		}
+		return []string{"~ value"}
+	}
+	var result []string
+	for key, newValue := range newFields {
+		oldValue, ok := oldFields[key]
+		switch {
+		case !ok:
+			result = append(result, "+ "+key)
+		case !reflect.DeepEqual(oldValue, newValue):
+			result = append(result, "~ "+key)
+		}
+	}
+	for key := range oldFields {
+		if _, ok := newFields[key]; !ok {
+			result = append(result, "- "+key)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i][2:] < result[j][2:] })
+	return result
+}
+
+// tagChanges returns the tags that are missing or different in stored
+func tagChanges(stored, tags map[string]string) map[string]string {
+	result := map[string]string{}
+	for key, value := range tags {
+		if stored[key] != value {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// kmsKeyARNPattern matches KMS key ARNs ex. arn:aws:kms:us-east-1:111122223333:key/1234abcd
+var kmsKeyARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:\d{12}:key/[A-Za-z0-9-]+$`)
+
+// sameKMSKey returns true if the configured key ARN is the described KMS key of a secret. A
+// secret created with a key ID is described with the key ID, which matches the end of the
+// ARN. Aliases can't be resolved without KMS, so they're treated as the same key
+func sameKMSKey(kmsKeyID, described string) bool {
+	switch {
+	case kmsKeyID == described:
+		return true
+	case kmsKeyARNPattern.MatchString(described):
+		return false
+	case strings.HasPrefix(described, "alias/") || strings.Contains(described, ":alias/"):
+		return true
+	}
+	return strings.HasSuffix(kmsKeyID, ":key/"+described)
+}
+
+// PlanChange compares value, tags, replica regions and KMS key with the stored secret without
+// writing anything. Tags that are only on the stored secret are ignored because Tag never removes
+// them. Replicas are only compared when replicaRegions isn't empty and the KMS key when kmsKeyID
+// isn't empty. See sameKMSKey
+func PlanChange(ctx context.Context, st SecretStore, secretID, value string, tags map[string]string, replicaRegions []string, kmsKeyID string, overwrite bool) (Change, error) {
+	change := Change{SecretID: secretID}
+	description, err := st.Describe(ctx, secretID)
+	if errors.Is(err, ErrNotFound) {
+		change.Action = ActionCreate
+		change.Fields = fieldChanges("{}", value)
+		change.Tags = tagChanges(nil, tags)
+		change.Replicas = replicaFields(ReplicaChanges(Description{}, replicaRegions))
+		change.KmsKeyID = kmsKeyID
+		return change, nil
+	}
+	if err != nil {
+		return change, err
+	}
+	if description.ScheduledForDeletion() {
+		return change, scheduledForDeletion(secretID, description)
+	}
+	stored, err := st.Get(ctx, secretID)
+	if err != nil {
+		return change, err
+	}
+	change.VersionID = description.CurrentVersionID()
+	change.Fields = fieldChanges(stored, value)
+	change.Tags = tagChanges(description.Tags, tags)
+	if len(replicaRegions) > 0 {
+		change.Replicas = replicaFields(ReplicaChanges(description, replicaRegions))
+	}
+	if kmsKeyID != "" && !sameKMSKey(kmsKeyID, description.KmsKeyID) {
+		change.KmsKeyID = kmsKeyID
+	}
+	switch {
+	case len(change.Fields) == 0 && len(change.Tags) == 0 && len(change.Replicas) == 0 && change.KmsKeyID == "":
+		change.Action = ActionUnchanged
+	case !overwrite:
+		change.Action = ActionSkip
+	default:
+		change.Action = ActionUpdate
+	}
+	return change, nil
+}
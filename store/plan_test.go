@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPlanChange(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	secretID := "snowflake/testenv/mywarehouse/mytype"
+	value := `{"password":"one","username":"user"}`
+	tags := map[string]string{"Source": "secret-hoard"}
+
+	change, err := PlanChange(ctx, st, secretID, value, tags, nil, "", false)
+	if err != nil || change.Action != ActionCreate {
+		t.Fatalf("PlanChange() = %+v, %v, want create", change, err)
+	}
+	if _, err = st.Create(ctx, secretID, value, tags, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		value     string
+		tags      map[string]string
+		overwrite bool
+		action    Action
+		fields    []string
+	}{
+		{"same value", `{"username":"user","password":"one"}`, tags, true, ActionUnchanged, nil},
+		{"new password", `{"password":"two","username":"user"}`, tags, true, ActionUpdate, []string{"~ password"}},
+		{"no overwrite", `{"password":"two","username":"user"}`, tags, false, ActionSkip, []string{"~ password"}},
+		{"new field", `{"host":"h","password":"one"}`, tags, true, ActionUpdate, []string{"+ host", "- username"}},
+		{"new tag", value, map[string]string{"Access": "read"}, true, ActionUpdate, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, err := PlanChange(ctx, st, secretID, tt.value, tt.tags, nil, "", tt.overwrite)
+			if err != nil {
+				t.Fatalf("PlanChange() error = %v", err)
+			}
+			if change.Action != tt.action || !reflect.DeepEqual(change.Fields, tt.fields) {
+				t.Errorf("PlanChange() = %+v, want %s %v", change, tt.action, tt.fields)
+			}
+		})
+	}
+}
+
+func TestPlanChangeKMSKey(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	value := `{"password":"one"}`
+	kmsKeyID := "arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+	tests := []struct {
+		name     string
+		created  string
+		action   Action
+		kmsKeyID string
+	}{
+		{"same key ARN", kmsKeyID, ActionUnchanged, ""},
+		{"key ID", "1234abcd-12ab-34cd-56ef-1234567890ab", ActionUnchanged, ""},
+		{"alias ARN", "arn:aws:kms:us-east-1:111122223333:alias/testenv", ActionUnchanged, ""},
+		{"alias name", "alias/testenv", ActionUnchanged, ""},
+		{"other key ARN", "arn:aws:kms:us-east-1:111122223333:key/other", ActionUpdate, kmsKeyID},
+		{"other key ID", "other", ActionUpdate, kmsKeyID},
+		{"default key", "", ActionUpdate, kmsKeyID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secretID := "snowflake/testenv/" + tt.name
+			if _, err := st.Create(ctx, secretID, value, nil, tt.created); err != nil {
+				t.Fatal(err)
+			}
+			change, err := PlanChange(ctx, st, secretID, value, nil, nil, kmsKeyID, true)
+			if err != nil {
+				t.Fatalf("PlanChange() error = %v", err)
+			}
+			if change.Action != tt.action || change.KmsKeyID != tt.kmsKeyID {
+				t.Errorf("PlanChange() = %+v, want %s %q", change, tt.action, tt.kmsKeyID)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimits are the requests per second allowed by each Secrets Manager API quota
+type RateLimits struct {
+	Read     float64 // DescribeSecret and GetSecretValue
+	List     float64 // ListSecrets pages
+	Versions float64 // ListSecretVersionIds pages
+	Write    float64 // CreateSecret, PutSecretValue, UpdateSecret, TagResource, DeleteSecret, RestoreSecret and UpdateSecretVersionStage
+}
+
+// DefaultRateLimits stay under the default Secrets Manager quotas: 10000 requests per second
+// for DescribeSecret and GetSecretValue, 100 for ListSecrets, 50 for ListSecretVersionIds and
+// 50 for the other operations. The quotas are shared by every client in the account and
+// region, so the limits leave room
+var DefaultRateLimits = RateLimits{Read: 5000, List: 50, Versions: 40, Write: 40}
+
+// pagedStore is implemented by stores whose List and Versions make one request per page. wait
+// is called before every request so each page takes a token
+type pagedStore interface {
+	ListPages(ctx context.Context, tagFilters map[string]string, wait func(context.Context) error) ([]Description, error)
+	VersionPages(ctx context.Context, secretID string, wait func(context.Context) error) ([]Version, error)
+}
+
+// RateLimitedStore limits the requests made to Store with a token bucket per API quota. Calls
+// wait for a token and fail when their context is done first
+type RateLimitedStore struct {
+	Store    SecretStore
+	read     *rate.Limiter
+	list     *rate.Limiter
+	versions *rate.Limiter
+	write    *rate.Limiter
+}
+
+// NewRateLimitedStore returns a RateLimitedStore. The bucket size of each quota is a tenth of a
+// second of requests so short bursts don't wait
+func NewRateLimitedStore(st SecretStore, limits RateLimits) *RateLimitedStore {
+	return &RateLimitedStore{
+		Store:    st,
+		read:     newLimiter(limits.Read),
+		list:     newLimiter(limits.List),
+		versions: newLimiter(limits.Versions),
+		write:    newLimiter(limits.Write),
+	}
+}
+
+// newLimiter returns a limiter with a burst of a tenth of a second of requests
+func newLimiter(perSecond float64) *rate.Limiter {
+	burst := int(perSecond / 10)
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), burst)
+}
+
+// Describe implements SecretStore
+func (r *RateLimitedStore) Describe(ctx context.Context, secretID string) (Description, error) {
+	if err := r.read.Wait(ctx); err != nil {
+		return Description{}, err
+	}
+	return r.Store.Describe(ctx, secretID)
+}
+
+// Get implements SecretStore
+func (r *RateLimitedStore) Get(ctx context.Context, secretID string) (string, error) {
+	if err := r.read.Wait(ctx); err != nil {
+		return "", err
+	}
+	return r.Store.Get(ctx, secretID)
+}
+
+// GetVersion implements SecretStore
+func (r *RateLimitedStore) GetVersion(ctx context.Context, secretID, versionID, stage string) (string, error) {
+	if err := r.read.Wait(ctx); err != nil {
+		return "", err
+	}
+	return r.Store.GetVersion(ctx, secretID, versionID, stage)
+}
+
+// Create implements SecretStore
+func (r *RateLimitedStore) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (string, error) {
+	if err := r.write.Wait(ctx); err != nil {
+		return "", err
+	}
+	return r.Store.Create(ctx, secretID, value, tags, kmsKeyID)
+}
+
+// Put implements SecretStore
+func (r *RateLimitedStore) Put(ctx context.Context, secretID, value string) (string, error) {
+	if err := r.write.Wait(ctx); err != nil {
+		return "", err
+	}
+	return r.Store.Put(ctx, secretID, value)
+}
+
+// Tag implements SecretStore
+func (r *RateLimitedStore) Tag(ctx context.Context, secretID string, tags map[string]string) error {
+	if err := r.write.Wait(ctx); err != nil {
+		return err
+	}
+	return r.Store.Tag(ctx, secretID, tags)
+}
+
+// Delete implements SecretStore
+func (r *RateLimitedStore) Delete(ctx context.Context, secretID string) error {
+	if err := r.write.Wait(ctx); err != nil {
+		return err
+	}
+	return r.Store.Delete(ctx, secretID)
+}
+
+// ScheduleDeletion implements SecretStore
+func (r *RateLimitedStore) ScheduleDeletion(ctx context.Context, secretID string, recoveryWindowDays int) (time.Time, error) {
+	if err := r.write.Wait(ctx); err != nil {
+		return time.Time{}, err
+	}
+	return r.Store.ScheduleDeletion(ctx, secretID, recoveryWindowDays)
+}
+
+// ReplicateRegions implements SecretStore
+func (r *RateLimitedStore) ReplicateRegions(ctx context.Context, secretID string, regions []string) error {
+	if err := r.write.Wait(ctx); err != nil {
+		return err
+	}
+	return r.Store.ReplicateRegions(ctx, secretID, regions)
+}
+
+// RemoveReplicaRegions implements SecretStore
+func (r *RateLimitedStore) RemoveReplicaRegions(ctx context.Context, secretID string, regions []string) error {
+	if err := r.write.Wait(ctx); err != nil {
+		return err
+	}
+	return r.Store.RemoveReplicaRegions(ctx, secretID, regions)
+}
+
+// SetKMSKey implements SecretStore
+func (r *RateLimitedStore) SetKMSKey(ctx context.Context, secretID, kmsKeyID string) error {
+	if err := r.write.Wait(ctx); err != nil {
+		return err
+	}
+	return r.Store.SetKMSKey(ctx, secretID, kmsKeyID)
+}
+
+// Restore implements SecretStore
+func (r *RateLimitedStore) Restore(ctx context.Context, secretID string) error {
+	if err := r.write.Wait(ctx); err != nil {
+		return err
+	}
+	return r.Store.Restore(ctx, secretID)
+}
+
+// List implements SecretStore. Every page of a pagedStore takes a token
+func (r *RateLimitedStore) List(ctx context.Context, tagFilters map[string]string) ([]Description, error) {
+	if paged, ok := r.Store.(pagedStore); ok {
+		return paged.ListPages(ctx, tagFilters, r.list.Wait)
+	}
+	if err := r.list.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.Store.List(ctx, tagFilters)
+}
+
+// Versions implements SecretStore. Every page of a pagedStore takes a token
+func (r *RateLimitedStore) Versions(ctx context.Context, secretID string) ([]Version, error) {
+	if paged, ok := r.Store.(pagedStore); ok {
+		return paged.VersionPages(ctx, secretID, r.versions.Wait)
+	}
+	if err := r.versions.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.Store.Versions(ctx, secretID)
+}
+
+// UpdateVersionStage implements SecretStore
+func (r *RateLimitedStore) UpdateVersionStage(ctx context.Context, secretID, stage, moveToVersionID, removeFromVersionID string) error {
+	if err := r.write.Wait(ctx); err != nil {
+		return err
+	}
+	return r.Store.UpdateVersionStage(ctx, secretID, stage, moveToVersionID, removeFromVersionID)
+}
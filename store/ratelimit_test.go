@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedStore(t *testing.T) {
+	testSecretStore(t, NewRateLimitedStore(NewMemoryStore(), DefaultRateLimits))
+}
+
+func TestRateLimitedStoreWaits(t *testing.T) {
+	st := NewRateLimitedStore(NewMemoryStore(), RateLimits{Read: 1000, List: 1000, Versions: 1000, Write: 0.01})
+	ctx := context.Background()
+	if _, err := st.Create(ctx, "one", "value", nil, ""); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	// the write bucket is empty for the next 100 seconds
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := st.Put(ctx, "one", "two"); err == nil {
+		t.Fatal("Put() error = nil, want rate limit wait error")
+	}
+	// reads use their own bucket
+	if value, err := st.Get(context.Background(), "one"); err != nil || value != "value" {
+		t.Fatalf("Get() = %q, %v", value, err)
+	}
+	if _, err := st.Describe(context.Background(), "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Describe() error = %v, want ErrNotFound", err)
+	}
+}
+
+// pagesStore returns List and Versions in pages pages
+type pagesStore struct {
+	*MemoryStore
+	pages int
+}
+
+func (p pagesStore) ListPages(ctx context.Context, tagFilters map[string]string, wait func(context.Context) error) ([]Description, error) {
+	for i := 0; i < p.pages; i++ {
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p.List(ctx, tagFilters)
+}
+
+func (p pagesStore) VersionPages(ctx context.Context, secretID string, wait func(context.Context) error) ([]Version, error) {
+	for i := 0; i < p.pages; i++ {
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return p.Versions(ctx, secretID)
+}
+
+func TestRateLimitedStorePages(t *testing.T) {
+	// a burst of one token and no new token for 100 seconds
+	st := NewRateLimitedStore(pagesStore{NewMemoryStore(), 2}, RateLimits{Read: 1000, List: 0.01, Versions: 0.01, Write: 1000})
+	if _, err := st.Create(context.Background(), "one", "value", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := st.List(ctx, nil); err == nil {
+		t.Error("List() error = nil, want a rate limit wait error on the second page")
+	}
+	if _, err := st.Versions(ctx, "one"); err == nil {
+		t.Error("Versions() error = nil, want a rate limit wait error on the second page")
+	}
+
+	// one page fits the burst and Versions doesn't use the write bucket
+	st = NewRateLimitedStore(pagesStore{NewMemoryStore(), 1}, RateLimits{Read: 1000, List: 0.01, Versions: 0.01, Write: 0.01})
+	if _, err := st.Create(context.Background(), "one", "value", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.List(ctx, nil); err != nil {
+		t.Errorf("List() error = %v", err)
+	}
+	if _, err := st.Versions(ctx, "one"); err != nil {
+		t.Errorf("Versions() error = %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"sort"
+)
+
+// ReplicaChanges returns the regions that must be added to and removed from the replicas of
+// the secret so it's replicated to exactly the regions. Both are sorted
+func ReplicaChanges(description Description, regions []string) (add, remove []string) {
+	current := map[string]bool{}
+	for _, replica := range description.Replicas {
+		current[replica.Region] = true
+	}
+	wanted := map[string]bool{}
+	for _, region := range regions {
+		wanted[region] = true
+		if !current[region] {
+			add = append(add, region)
+		}
+	}
+	for region := range current {
+		if !wanted[region] {
+			remove = append(remove, region)
+		}
+	}
+	sort.Strings(add)
+	sort.Strings(remove)
+	return add, remove
+}
+
+// replicaFields returns the replica changes as regions prefixed with + or -
+func replicaFields(add, remove []string) []string {
+	var result []string
+	for _, region := range add {
+		result = append(result, "+ "+region)
+	}
+	for _, region := range remove {
+		result = append(result, "- "+region)
+	}
+	return result
+}
+
+// ReconcileReplicas replicates a secret to exactly the regions: missing replicas are created
+// and the replicas in other regions are removed
+func ReconcileReplicas(ctx context.Context, st SecretStore, secretID string, regions []string) error {
+	description, err := st.Describe(ctx, secretID)
+	if err != nil {
+		return err
+	}
+	add, remove := ReplicaChanges(description, regions)
+	if len(add) > 0 {
+		if err = st.ReplicateRegions(ctx, secretID, add); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		return st.RemoveReplicaRegions(ctx, secretID, remove)
+	}
+	return nil
+}
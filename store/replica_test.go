@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReconcileReplicas(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	secretID := "text_file/testenv/my_file_type"
+	value := `{"contents":"one"}`
+	regions := []string{"us-west-2", "eu-west-1"}
+
+	change, err := PlanChange(ctx, st, secretID, value, nil, regions, "", false)
+	if err != nil || !reflect.DeepEqual(change.Replicas, []string{"+ eu-west-1", "+ us-west-2"}) {
+		t.Fatalf("PlanChange() = %+v, %v, want 2 replicas", change, err)
+	}
+	if _, err = st.Create(ctx, secretID, value, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = ReconcileReplicas(ctx, st, secretID, regions); err != nil {
+		t.Fatalf("ReconcileReplicas() error = %v", err)
+	}
+	if change, err = PlanChange(ctx, st, secretID, value, nil, regions, "", false); err != nil || change.Action != ActionUnchanged {
+		t.Fatalf("PlanChange() = %+v, %v, want unchanged", change, err)
+	}
+	// replicas aren't compared without regions
+	if change, err = PlanChange(ctx, st, secretID, value, nil, nil, "", false); err != nil || change.Action != ActionUnchanged {
+		t.Fatalf("PlanChange(nil) = %+v, %v, want unchanged", change, err)
+	}
+
+	regions = []string{"us-west-2", "ap-south-1"}
+	change, err = PlanChange(ctx, st, secretID, value, nil, regions, "", true)
+	if err != nil || change.Action != ActionUpdate || !reflect.DeepEqual(change.Replicas, []string{"+ ap-south-1", "- eu-west-1"}) {
+		t.Fatalf("PlanChange() = %+v, %v, want replicas to update", change, err)
+	}
+	if err = ReconcileReplicas(ctx, st, secretID, regions); err != nil {
+		t.Fatalf("ReconcileReplicas() error = %v", err)
+	}
+	description, err := st.Describe(ctx, secretID)
+	if err != nil || !reflect.DeepEqual(description.ReplicaRegions(), []string{"ap-south-1", "us-west-2"}) {
+		t.Errorf("ReplicaRegions() = %v, %v", description.ReplicaRegions(), err)
+	}
+	if err = st.RemoveReplicaRegions(ctx, secretID, []string{"eu-west-1"}); !errors.Is(err, ErrInvalidRequest) {
+		t.Errorf("RemoveReplicaRegions() error = %v, want ErrInvalidRequest", err)
+	}
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+)
+
+// RetryPolicy is how often and how long RetryStore retries transient errors
+type RetryPolicy struct {
+	MaxAttempts int           // attempts per call including the first one. 1 or less disables retries
+	BaseDelay   time.Duration // upper bound of the first backoff. it doubles after every attempt
+	MaxDelay    time.Duration // upper bound of every backoff
+}
+
+// DefaultRetryPolicy retries a call up to 4 times and waits at most 7.5 seconds in total
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 5, BaseDelay: 500 * time.Millisecond, MaxDelay: 10 * time.Second}
+
+// retryableCodes are the error codes of throttling and server side errors
+var retryableCodes = map[string]bool{
+	"ThrottlingException":      true,
+	"Throttling":               true,
+	"TooManyRequestsException": true,
+	"RequestLimitExceeded":     true,
+	"InternalServiceError":     true,
+	"InternalFailure":          true,
+	"ServiceUnavailable":       true,
+	"RequestTimeout":           true,
+	"RequestTimeoutException":  true,
+}
+
+// Retryable returns true for throttling, server side and network errors
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && retryableCodes[apiErr.ErrorCode()] {
+		return true
+	}
+	// connection errors and 5xx responses
+	return retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary
+}
+
+// backoff returns the jittered delay before the retry after attempt. The delay is random
+// between zero and the exponential bound so clients that were throttled together spread out
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	bound := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		bound *= 2
+		if p.MaxDelay > 0 && bound >= p.MaxDelay {
+			break
+		}
+	}
+	if p.MaxDelay > 0 && bound > p.MaxDelay {
+		bound = p.MaxDelay
+	}
+	if bound <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(bound)))
+}
+
+// RetryStore retries the calls to Store that fail with a Retryable error. It counts the
+// retries so callers can report them
+type RetryStore struct {
+	Store   SecretStore // defaults to Default() when nil
+	Policy  RetryPolicy
+	retries atomic.Int64
+}
+
+// NewRetryStore returns a RetryStore
+func NewRetryStore(st SecretStore, policy RetryPolicy) *RetryStore {
+	return &RetryStore{Store: st, Policy: policy}
+}
+
+// Retries returns the number of retried calls
+func (r *RetryStore) Retries() int {
+	return int(r.retries.Load())
+}
+
+// withRetries calls fn until it succeeds, fails with an error that isn't Retryable or runs
+// out of attempts
+func withRetries[T any](ctx context.Context, r *RetryStore, fn func(st SecretStore) (T, error)) (result T, err error) {
+	st, err := OrDefault(r.Store)
+	if err != nil {
+		return result, err
+	}
+	for attempt := 1; ; attempt++ {
+		result, err = fn(st)
+		if err == nil || attempt >= r.Policy.MaxAttempts || !Retryable(err) {
+			return result, err
+		}
+		timer := time.NewTimer(r.Policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		r.retries.Add(1)
+	}
+}
+
+// Describe implements SecretStore
+func (r *RetryStore) Describe(ctx context.Context, secretID string) (Description, error) {
+	return withRetries(ctx, r, func(st SecretStore) (Description, error) {
+		return st.Describe(ctx, secretID)
+	})
+}
+
+// Get implements SecretStore
+func (r *RetryStore) Get(ctx context.Context, secretID string) (string, error) {
+	return withRetries(ctx, r, func(st SecretStore) (string, error) {
+		return st.Get(ctx, secretID)
+	})
+}
+
+// GetVersion implements SecretStore
+func (r *RetryStore) GetVersion(ctx context.Context, secretID, versionID, stage string) (string, error) {
+	return withRetries(ctx, r, func(st SecretStore) (string, error) {
+		return st.GetVersion(ctx, secretID, versionID, stage)
+	})
+}
+
+// clientRequestTokenKey is the context key of the ClientRequestToken of a write
+type clientRequestTokenKey struct{}
+
+// WithClientRequestToken returns a context whose Create and Put calls send token as the
+// ClientRequestToken, so calls repeated with the same token write at most one version
+func WithClientRequestToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, clientRequestTokenKey{}, token)
+}
+
+// ClientRequestToken returns the ClientRequestToken of ctx or an empty string
+func ClientRequestToken(ctx context.Context) string {
+	token, _ := ctx.Value(clientRequestTokenKey{}).(string)
+	return token
+}
+
+// idempotent returns ctx with a new ClientRequestToken unless it already has one
+func idempotent(ctx context.Context) context.Context {
+	if ClientRequestToken(ctx) != "" {
+		return ctx
+	}
+	return WithClientRequestToken(ctx, newVersionID())
+}
+
+// Create implements SecretStore. Every attempt sends the same ClientRequestToken. A retry that
+// finds the secret with the version of the token succeeds: the failed attempt created it
+func (r *RetryStore) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (string, error) {
+	ctx = idempotent(ctx)
+	token := ClientRequestToken(ctx)
+	attempts := 0
+	return withRetries(ctx, r, func(st SecretStore) (string, error) {
+		attempts++
+		versionID, err := st.Create(ctx, secretID, value, tags, kmsKeyID)
+		if attempts > 1 && errors.Is(err, ErrExists) {
+			if description, describeErr := st.Describe(ctx, secretID); describeErr == nil {
+				if _, ok := description.VersionStages[token]; ok {
+					return token, nil
+				}
+			}
+		}
+		return versionID, err
+	})
+}
+
+// Put implements SecretStore. Every attempt sends the same ClientRequestToken so a retry of
+// a write that succeeded doesn't add a second version
+func (r *RetryStore) Put(ctx context.Context, secretID, value string) (string, error) {
+	ctx = idempotent(ctx)
+	return withRetries(ctx, r, func(st SecretStore) (string, error) {
+		return st.Put(ctx, secretID, value)
+	})
+}
+
+// Tag implements SecretStore
+func (r *RetryStore) Tag(ctx context.Context, secretID string, tags map[string]string) error {
+	_, err := withRetries(ctx, r, func(st SecretStore) (struct{}, error) {
+		return struct{}{}, st.Tag(ctx, secretID, tags)
+	})
+	return err
+}
+
+// Delete implements SecretStore
+func (r *RetryStore) Delete(ctx context.Context, secretID string) error {
+	_, err := withRetries(ctx, r, func(st SecretStore) (struct{}, error) {
+		return struct{}{}, st.Delete(ctx, secretID)
+	})
+	return err
+}
+
+// ScheduleDeletion implements SecretStore
+func (r *RetryStore) ScheduleDeletion(ctx context.Context, secretID string, recoveryWindowDays int) (time.Time, error) {
+	return withRetries(ctx, r, func(st SecretStore) (time.Time, error) {
+		return st.ScheduleDeletion(ctx, secretID, recoveryWindowDays)
+	})
+}
+
+// ReplicateRegions implements SecretStore
+func (r *RetryStore) ReplicateRegions(ctx context.Context, secretID string, regions []string) error {
+	_, err := withRetries(ctx, r, func(st SecretStore) (struct{}, error) {
+		return struct{}{}, st.ReplicateRegions(ctx, secretID, regions)
+	})
+	return err
+}
+
+// RemoveReplicaRegions implements SecretStore
+func (r *RetryStore) RemoveReplicaRegions(ctx context.Context, secretID string, regions []string) error {
+	_, err := withRetries(ctx, r, func(st SecretStore) (struct{}, error) {
+		return struct{}{}, st.RemoveReplicaRegions(ctx, secretID, regions)
+	})
+	return err
+}
+
+// SetKMSKey implements SecretStore
+func (r *RetryStore) SetKMSKey(ctx context.Context, secretID, kmsKeyID string) error {
+	_, err := withRetries(ctx, r, func(st SecretStore) (struct{}, error) {
+		return struct{}{}, st.SetKMSKey(ctx, secretID, kmsKeyID)
+	})
+	return err
+}
+
+// Restore implements SecretStore
+func (r *RetryStore) Restore(ctx context.Context, secretID string) error {
+	_, err := withRetries(ctx, r, func(st SecretStore) (struct{}, error) {
+		return struct{}{}, st.Restore(ctx, secretID)
+	})
+	return err
+}
+
+// List implements SecretStore
+func (r *RetryStore) List(ctx context.Context, tagFilters map[string]string) ([]Description, error) {
+	return withRetries(ctx, r, func(st SecretStore) ([]Description, error) {
+		return st.List(ctx, tagFilters)
+	})
+}
+
+// Versions implements SecretStore
+func (r *RetryStore) Versions(ctx context.Context, secretID string) ([]Version, error) {
+	return withRetries(ctx, r, func(st SecretStore) ([]Version, error) {
+		return st.Versions(ctx, secretID)
+	})
+}
+
+// UpdateVersionStage implements SecretStore
+func (r *RetryStore) UpdateVersionStage(ctx context.Context, secretID, stage, moveToVersionID, removeFromVersionID string) error {
+	_, err := withRetries(ctx, r, func(st SecretStore) (struct{}, error) {
+		return struct{}{}, st.UpdateVersionStage(ctx, secretID, stage, moveToVersionID, removeFromVersionID)
+	})
+	return err
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+)
+
+// flakyStore fails the first failures calls to Put with err
+type flakyStore struct {
+	*MemoryStore
+	failures int
+	err      error
+	calls    int
+}
+
+func (f *flakyStore) Put(ctx context.Context, secretID, value string) (string, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return "", f.err
+	}
+	return f.MemoryStore.Put(ctx, secretID, value)
+}
+
+// ambiguousStore writes the first Create and Put and then fails them with err like a request
+// that timed out after Secrets Manager stored the value
+type ambiguousStore struct {
+	*MemoryStore
+	err     error
+	creates int
+	puts    int
+}
+
+func (a *ambiguousStore) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (string, error) {
+	a.creates++
+	versionID, err := a.MemoryStore.Create(ctx, secretID, value, tags, kmsKeyID)
+	if a.creates == 1 && err == nil {
+		return "", a.err
+	}
+	return versionID, err
+}
+
+func (a *ambiguousStore) Put(ctx context.Context, secretID, value string) (string, error) {
+	a.puts++
+	versionID, err := a.MemoryStore.Put(ctx, secretID, value)
+	if a.puts == 1 && err == nil {
+		return "", a.err
+	}
+	return versionID, err
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&smithy.GenericAPIError{Code: "ThrottlingException"}, true},
+		{fmt.Errorf("wrapped: %w", &smithy.GenericAPIError{Code: "InternalServiceError"}), true},
+		{&smithy.GenericAPIError{Code: "AccessDeniedException"}, false},
+		{fmt.Errorf("%w: missing", ErrNotFound), false},
+		{context.Canceled, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryStore(t *testing.T) {
+	testSecretStore(t, NewRetryStore(NewMemoryStore(), DefaultRetryPolicy))
+
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	throttled := &smithy.GenericAPIError{Code: "ThrottlingException"}
+	flaky := &flakyStore{MemoryStore: NewMemoryStore(), failures: 2, err: throttled}
+	if _, err := flaky.Create(ctx, "one", "value", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	st := NewRetryStore(flaky, policy)
+	if _, err := st.Put(ctx, "one", "two"); err != nil || st.Retries() != 2 {
+		t.Fatalf("Put() error = %v, retries = %d, want 2 retries", err, st.Retries())
+	}
+
+	// out of attempts
+	flaky.calls, flaky.failures = 0, 3
+	st = NewRetryStore(flaky, policy)
+	if _, err := st.Put(ctx, "one", "three"); !errors.Is(err, throttled) || st.Retries() != 2 || flaky.calls != 3 {
+		t.Fatalf("Put() error = %v, retries = %d, calls = %d", err, st.Retries(), flaky.calls)
+	}
+
+	// errors that aren't transient aren't retried
+	flaky.calls, flaky.err = 0, errors.New("AccessDeniedException")
+	st = NewRetryStore(flaky, policy)
+	if _, err := st.Put(ctx, "one", "four"); err == nil || st.Retries() != 0 || flaky.calls != 1 {
+		t.Fatalf("Put() error = %v, retries = %d, calls = %d", err, st.Retries(), flaky.calls)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 10, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt := 1; attempt < 10; attempt++ {
+		if delay := policy.backoff(attempt); delay < 0 || delay >= time.Second {
+			t.Errorf("backoff(%d) = %v, want less than MaxDelay", attempt, delay)
+		}
+	}
+	if delay := (RetryPolicy{}).backoff(3); delay != 0 {
+		t.Errorf("backoff() = %v, want 0 without delays", delay)
+	}
+}
+
+func TestRetryStoreIdempotentWrites(t *testing.T) {
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	ambiguous := &ambiguousStore{MemoryStore: NewMemoryStore(), err: &smithy.GenericAPIError{Code: "RequestTimeout"}}
+	st := NewRetryStore(ambiguous, policy)
+
+	first, err := st.Create(ctx, "one", "value", nil, "")
+	if err != nil || first == "" || ambiguous.creates != 2 {
+		t.Fatalf("Create() = %q, %v, creates = %d, want the version of the first attempt", first, err, ambiguous.creates)
+	}
+	second, err := st.Put(ctx, "one", "two")
+	if err != nil || ambiguous.puts != 2 {
+		t.Fatalf("Put() = %q, %v, puts = %d", second, err, ambiguous.puts)
+	}
+	versions, err := st.Versions(ctx, "one")
+	if err != nil || len(versions) != 2 {
+		t.Fatalf("Versions() = %+v, %v, want 2 versions", versions, err)
+	}
+	// the retried Put didn't push the first value out of AWSPREVIOUS
+	if value, err := st.GetVersion(ctx, "one", "", StagePrevious); err != nil || value != "value" {
+		t.Errorf("GetVersion(AWSPREVIOUS) = %q, %v, want \"value\"", value, err)
+	}
+	if description, err := st.Describe(ctx, "one"); err != nil || description.CurrentVersionID() != second {
+		t.Errorf("AWSCURRENT = %s, %v, want %s", description.CurrentVersionID(), err, second)
+	}
+}
@@ -0,0 +1,412 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+)
+
+// SecretsManager implements SecretStore with AWS Secrets Manager
+type SecretsManager struct {
+	Client *secretsmanager.Client
+}
+
+var (
+	defaultOnce  sync.Once
+	defaultStore SecretStore
+	defaultErr   error
+)
+
+// NewSecretsManager returns a SecretsManager store using the default AWS SDK configuration
+// changed by optFns
+func NewSecretsManager(ctx context.Context, optFns ...func(*config.LoadOptions) error) (SecretsManager, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		return SecretsManager{}, fmt.Errorf("unable to load SDK config, %w", err)
+	}
+	return SecretsManager{Client: secretsmanager.NewFromConfig(cfg)}, nil
+}
+
+// Default returns a shared SecretsManager store limited to DefaultRateLimits. The SDK
+// configuration is only loaded once and every caller shares the client and rate limits.
+// The SDK doesn't retry failed requests, wrap the store with NewRetryStore to retry them
+// with a RetryPolicy
+func Default() (SecretStore, error) {
+	defaultOnce.Do(func() {
+		var sm SecretsManager
+		sm, defaultErr = NewSecretsManager(context.Background(), config.WithRetryer(func() aws.Retryer {
+			return aws.NopRetryer{}
+		}))
+		defaultStore = NewRateLimitedStore(sm, DefaultRateLimits)
+	})
+	return defaultStore, defaultErr
+}
+
+// ForProfile returns a SecretsManager store of an AWS profile and region limited to
+// DefaultRateLimits like Default. An empty profile or region keeps the default one. Unlike
+// Default every call loads the SDK configuration and has its own rate limits
+func ForProfile(profile, region string) (SecretStore, error) {
+	optFns := []func(*config.LoadOptions) error{config.WithRetryer(func() aws.Retryer {
+		return aws.NopRetryer{}
+	})}
+	if profile != "" {
+		optFns = append(optFns, config.WithSharedConfigProfile(profile))
+	}
+	if region != "" {
+		optFns = append(optFns, config.WithRegion(region))
+	}
+	sm, err := NewSecretsManager(context.Background(), optFns...)
+	if err != nil {
+		return nil, err
+	}
+	return NewRateLimitedStore(sm, DefaultRateLimits), nil
+}
+
+// Region returns the region of the client
+func (s SecretsManager) Region() string {
+	return s.Client.Options().Region
+}
+
+// Region returns the AWS region of st through RateLimitedStore and RetryStore wrappers. It's
+// empty for stores that aren't in a region
+func Region(st SecretStore) string {
+	switch st := st.(type) {
+	case interface{ Region() string }:
+		return st.Region()
+	case *RateLimitedStore:
+		return Region(st.Store)
+	case *RetryStore:
+		return Region(st.Store)
+	}
+	return ""
+}
+
+// OrDefault returns st or the Default store if st is nil
+func OrDefault(st SecretStore) (SecretStore, error) {
+	if st != nil {
+		return st, nil
+	}
+	return Default()
+}
+
+// ConvertMapToTags Convert a map to a list of tags
+func ConvertMapToTags(tags map[string]string) []types.Tag {
+	var tagList []types.Tag
+	for key, value := range tags {
+		tag := types.Tag{
+			Key:   aws.String(key),
+			Value: aws.String(value),
+		}
+		tagList = append(tagList, tag)
+	}
+	return tagList
+}
+
+// ConvertTagsToMap converts a list of tags to a map
+func ConvertTagsToMap(tags []types.Tag) map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	return result
+}
+
+// wrapError wraps ResourceNotFoundException, ResourceExistsException and invalid
+// parameter/request errors with ErrNotFound, ErrExists and ErrInvalidRequest. Invalid
+// requests on secrets scheduled for deletion also wrap ErrScheduledForDeletion
+func wrapError(err error) error {
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	}
+	var exists *types.ResourceExistsException
+	if errors.As(err, &exists) {
+		return fmt.Errorf("%w: %w", ErrExists, err)
+	}
+	var invalidParameter *types.InvalidParameterException
+	var invalidRequest *types.InvalidRequestException
+	if errors.As(err, &invalidRequest) && strings.Contains(invalidRequest.ErrorMessage(), "for deletion") {
+		return fmt.Errorf("%w: %w: %w", ErrInvalidRequest, ErrScheduledForDeletion, err)
+	}
+	if errors.As(err, &invalidParameter) || errors.As(err, &invalidRequest) {
+		return fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+	}
+	return err
+}
+
+// Describe calls DescribeSecret
+func (s SecretsManager) Describe(ctx context.Context, secretID string) (Description, error) {
+	output, err := s.Client.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(secretID),
+	})
+	if err != nil {
+		return Description{}, wrapError(err)
+	}
+	return Description{
+		Name:            aws.ToString(output.Name),
+		ARN:             aws.ToString(output.ARN),
+		Tags:            ConvertTagsToMap(output.Tags),
+		LastChangedDate: output.LastChangedDate,
+		DeletedDate:     output.DeletedDate,
+		VersionStages:   output.VersionIdsToStages,
+		Replicas:        convertReplicationStatus(output.ReplicationStatus),
+		KmsKeyID:        aws.ToString(output.KmsKeyId),
+	}, nil
+}
+
+// convertReplicationStatus converts the replication status of DescribeSecret to Replicas
+// sorted by region
+func convertReplicationStatus(statuses []types.ReplicationStatusType) []Replica {
+	var result []Replica
+	for _, status := range statuses {
+		result = append(result, Replica{
+			Region:        aws.ToString(status.Region),
+			Status:        string(status.Status),
+			StatusMessage: aws.ToString(status.StatusMessage),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Region < result[j].Region })
+	return result
+}
+
+// Get calls GetSecretValue
+func (s SecretsManager) Get(ctx context.Context, secretID string) (string, error) {
+	return s.GetVersion(ctx, secretID, "", "")
+}
+
+// GetVersion calls GetSecretValue with the version ID and stage
+func (s SecretsManager) GetVersion(ctx context.Context, secretID, versionID, stage string) (string, error) {
+	input := &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(secretID),
+	}
+	if versionID != "" {
+		input.VersionId = aws.String(versionID)
+	}
+	if stage != "" {
+		input.VersionStage = aws.String(stage)
+	}
+	output, err := s.Client.GetSecretValue(ctx, input)
+	if err != nil {
+		return "", wrapError(err)
+	}
+	if output.SecretString == nil {
+		return "", fmt.Errorf("secret value is nil")
+	}
+	return *output.SecretString, nil
+}
+
+// Create calls CreateSecret
+func (s SecretsManager) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (string, error) {
+	input := &secretsmanager.CreateSecretInput{
+		Name:         aws.String(secretID),
+		SecretString: aws.String(value),
+		Tags:         ConvertMapToTags(tags),
+	}
+	if kmsKeyID != "" {
+		input.KmsKeyId = aws.String(kmsKeyID)
+	}
+	if token := ClientRequestToken(ctx); token != "" {
+		input.ClientRequestToken = aws.String(token)
+	}
+	output, err := s.Client.CreateSecret(ctx, input)
+	if err != nil {
+		return "", wrapError(err)
+	}
+	return aws.ToString(output.VersionId), nil
+}
+
+// Put calls UpdateSecret to store a new value
+func (s SecretsManager) Put(ctx context.Context, secretID, value string) (string, error) {
+	input := &secretsmanager.UpdateSecretInput{
+		SecretId:     aws.String(secretID),
+		SecretString: aws.String(value),
+	}
+	if token := ClientRequestToken(ctx); token != "" {
+		input.ClientRequestToken = aws.String(token)
+	}
+	output, err := s.Client.UpdateSecret(ctx, input)
+	if err != nil {
+		return "", wrapError(err)
+	}
+	return aws.ToString(output.VersionId), nil
+}
+
+// SetKMSKey calls UpdateSecret with the KMS key. Secrets Manager re-encrypts the
+// AWSCURRENT, AWSPENDING and AWSPREVIOUS versions with it
+func (s SecretsManager) SetKMSKey(ctx context.Context, secretID, kmsKeyID string) error {
+	_, err := s.Client.UpdateSecret(ctx, &secretsmanager.UpdateSecretInput{
+		SecretId: aws.String(secretID),
+		KmsKeyId: aws.String(kmsKeyID),
+	})
+	return wrapError(err)
+}
+
+// Tag calls TagResource
+func (s SecretsManager) Tag(ctx context.Context, secretID string, tags map[string]string) error {
+	_, err := s.Client.TagResource(ctx, &secretsmanager.TagResourceInput{
+		SecretId: aws.String(secretID),
+		Tags:     ConvertMapToTags(tags),
+	})
+	return wrapError(err)
+}
+
+// Delete calls DeleteSecret with ForceDeleteWithoutRecovery
+func (s SecretsManager) Delete(ctx context.Context, secretID string) error {
+	_, err := s.Client.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(secretID),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	return wrapError(err)
+}
+
+// ScheduleDeletion calls DeleteSecret with a recovery window
+func (s SecretsManager) ScheduleDeletion(ctx context.Context, secretID string, recoveryWindowDays int) (time.Time, error) {
+	if err := checkRecoveryWindow(recoveryWindowDays); err != nil {
+		return time.Time{}, err
+	}
+	output, err := s.Client.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:             aws.String(secretID),
+		RecoveryWindowInDays: aws.Int64(int64(recoveryWindowDays)),
+	})
+	if err != nil {
+		return time.Time{}, wrapError(err)
+	}
+	return aws.ToTime(output.DeletionDate), nil
+}
+
+// Restore calls RestoreSecret
+func (s SecretsManager) Restore(ctx context.Context, secretID string) error {
+	_, err := s.Client.RestoreSecret(ctx, &secretsmanager.RestoreSecretInput{
+		SecretId: aws.String(secretID),
+	})
+	return wrapError(err)
+}
+
+// ReplicateRegions calls ReplicateSecretToRegions. The replicas use the default KMS key of
+// their region
+func (s SecretsManager) ReplicateRegions(ctx context.Context, secretID string, regions []string) error {
+	replicas := make([]types.ReplicaRegionType, len(regions))
+	for i, region := range regions {
+		replicas[i] = types.ReplicaRegionType{Region: aws.String(region)}
+	}
+	_, err := s.Client.ReplicateSecretToRegions(ctx, &secretsmanager.ReplicateSecretToRegionsInput{
+		SecretId:          aws.String(secretID),
+		AddReplicaRegions: replicas,
+	})
+	return wrapError(err)
+}
+
+// RemoveReplicaRegions calls RemoveRegionsFromReplication
+func (s SecretsManager) RemoveReplicaRegions(ctx context.Context, secretID string, regions []string) error {
+	_, err := s.Client.RemoveRegionsFromReplication(ctx, &secretsmanager.RemoveRegionsFromReplicationInput{
+		SecretId:             aws.String(secretID),
+		RemoveReplicaRegions: regions,
+	})
+	return wrapError(err)
+}
+
+// List calls ListSecrets filtered by tag keys and values. ListSecrets matches tag keys and
+// values independently so the results are filtered again on exact key/value pairs
+func (s SecretsManager) List(ctx context.Context, tagFilters map[string]string) ([]Description, error) {
+	return s.ListPages(ctx, tagFilters, nil)
+}
+
+// ListPages is List calling wait before every ListSecrets page. A nil wait doesn't wait
+func (s SecretsManager) ListPages(ctx context.Context, tagFilters map[string]string, wait func(context.Context) error) ([]Description, error) {
+	var filters []types.Filter
+	for key, value := range tagFilters {
+		filters = append(filters,
+			types.Filter{Key: types.FilterNameStringTypeTagKey, Values: []string{key}},
+			types.Filter{Key: types.FilterNameStringTypeTagValue, Values: []string{value}},
+		)
+	}
+	var result []Description
+	paginator := secretsmanager.NewListSecretsPaginator(s.Client, &secretsmanager.ListSecretsInput{
+		Filters: filters,
+	})
+	for paginator.HasMorePages() {
+		if wait != nil {
+			if err := wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		for _, entry := range page.SecretList {
+			tags := ConvertTagsToMap(entry.Tags)
+			if !MatchesTags(tags, tagFilters) {
+				continue
+			}
+			result = append(result, Description{
+				Name:            aws.ToString(entry.Name),
+				ARN:             aws.ToString(entry.ARN),
+				Tags:            tags,
+				LastChangedDate: entry.LastChangedDate,
+				DeletedDate:     entry.DeletedDate,
+				VersionStages:   entry.SecretVersionsToStages,
+			})
+		}
+	}
+	return result, nil
+}
+
+// Versions calls ListSecretVersionIds
+func (s SecretsManager) Versions(ctx context.Context, secretID string) ([]Version, error) {
+	return s.VersionPages(ctx, secretID, nil)
+}
+
+// VersionPages is Versions calling wait before every ListSecretVersionIds page. A nil wait
+// doesn't wait. The versions are sorted oldest first
+func (s SecretsManager) VersionPages(ctx context.Context, secretID string, wait func(context.Context) error) ([]Version, error) {
+	var result []Version
+	paginator := secretsmanager.NewListSecretVersionIdsPaginator(s.Client, &secretsmanager.ListSecretVersionIdsInput{
+		SecretId: aws.String(secretID),
+	})
+	for paginator.HasMorePages() {
+		if wait != nil {
+			if err := wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		for _, entry := range page.Versions {
+			result = append(result, Version{
+				VersionID:   aws.ToString(entry.VersionId),
+				Stages:      entry.VersionStages,
+				CreatedDate: entry.CreatedDate,
+			})
+		}
+	}
+	SortVersions(result)
+	return result, nil
+}
+
+// UpdateVersionStage calls UpdateSecretVersionStage
+func (s SecretsManager) UpdateVersionStage(ctx context.Context, secretID, stage, moveToVersionID, removeFromVersionID string) error {
+	input := &secretsmanager.UpdateSecretVersionStageInput{
+		SecretId:     aws.String(secretID),
+		VersionStage: aws.String(stage),
+	}
+	if moveToVersionID != "" {
+		input.MoveToVersionId = aws.String(moveToVersionID)
+	}
+	if removeFromVersionID != "" {
+		input.RemoveFromVersionId = aws.String(removeFromVersionID)
+	}
+	_, err := s.Client.UpdateSecretVersionStage(ctx, input)
+	return wrapError(err)
+}
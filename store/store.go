@@ -0,0 +1,183 @@
+// Package store defines the SecretStore interface used by the resource packages
+// and the uploader so they don't depend on a specific secret backend
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrNotFound is returned (wrapped) by a SecretStore when the secret does not exist
+var ErrNotFound = errors.New("secret not found")
+
+// ErrExists is returned (wrapped) by SecretStore.Create when the secret already exists
+var ErrExists = errors.New("secret already exists")
+
+// ErrInvalidRequest is returned (wrapped) by a SecretStore when the parameters don't match
+// the state of the secret ex. moving a version stage that is attached to another version
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ErrScheduledForDeletion is returned (wrapped) by Exists when the secret is scheduled for
+// deletion. The secret exists but can't be read or updated until it's restored. The stores
+// wrap it with ErrInvalidRequest when an operation fails because of it
+var ErrScheduledForDeletion = errors.New("secret is scheduled for deletion")
+
+// Recovery window limits of ScheduleDeletion in days
+const (
+	MinRecoveryWindowDays     = 7
+	MaxRecoveryWindowDays     = 30
+	DefaultRecoveryWindowDays = 30
+)
+
+// Version stages managed by the stores
+const (
+	StageCurrent  = "AWSCURRENT"
+	StagePrevious = "AWSPREVIOUS"
+)
+
+// Description describes a stored secret without its value
+type Description struct {
+	Name            string              // secret ID ex. rdspostgres/testenv/myinstance/mydb/mytype
+	ARN             string              // ARN or other store specific identifier
+	Tags            map[string]string   // secret tags
+	LastChangedDate *time.Time          // last time the value or metadata changed
+	DeletedDate     *time.Time          // set when the secret is scheduled for deletion
+	VersionStages   map[string][]string // version ID -> version stages ex. AWSCURRENT
+	Replicas        []Replica           // replicas of the secret in other regions sorted by region
+	KmsKeyID        string              // KMS key the value is encrypted with. empty is the aws/secretsmanager key
+}
+
+// Replica is the replication status of a secret in another region
+type Replica struct {
+	Region        string `json:"region"`                  // region of the replica ex. us-west-2
+	Status        string `json:"status"`                  // InSync, Failed or InProgress
+	StatusMessage string `json:"statusMessage,omitempty"` // reason of a Failed status
+}
+
+// ReplicaRegions returns the regions of the replicas
+func (d Description) ReplicaRegions() []string {
+	regions := make([]string, len(d.Replicas))
+	for i, replica := range d.Replicas {
+		regions[i] = replica.Region
+	}
+	return regions
+}
+
+// ScheduledForDeletion returns true if the secret is scheduled for deletion
+func (d Description) ScheduledForDeletion() bool {
+	return d.DeletedDate != nil
+}
+
+// Version is one version of a secret value
+type Version struct {
+	VersionID   string     // version ID
+	Stages      []string   // version stages ex. AWSCURRENT, AWSPREVIOUS
+	CreatedDate *time.Time // time the version was created
+}
+
+// SortVersions sorts versions oldest first. Versions without a creation date come first.
+// Secrets Manager doesn't list versions in any particular order
+func SortVersions(versions []Version) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if versions[i].CreatedDate == nil || versions[j].CreatedDate == nil {
+			return versions[i].CreatedDate == nil && versions[j].CreatedDate != nil
+		}
+		return versions[i].CreatedDate.Before(*versions[j].CreatedDate)
+	})
+}
+
+// SecretStore is the set of operations secret-hoard needs from a secret backend
+type SecretStore interface {
+	// Describe returns the description of a secret or ErrNotFound
+	Describe(ctx context.Context, secretID string) (Description, error)
+	// Get returns the current (AWSCURRENT) value of a secret
+	Get(ctx context.Context, secretID string) (string, error)
+	// GetVersion returns the value of the version with versionID or stage. Either may be empty
+	// and when both are set they must name the same version. Both empty is AWSCURRENT
+	GetVersion(ctx context.Context, secretID, versionID, stage string) (string, error)
+	// Create creates a new secret with a value and tags encrypted with the KMS key and returns
+	// the new version ID. An empty kmsKeyID is the aws/secretsmanager key
+	Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (versionID string, err error)
+	// Put stores a new current value for an existing secret and returns the new version ID
+	Put(ctx context.Context, secretID, value string) (versionID string, err error)
+	// Tag adds or overwrites tags on an existing secret
+	Tag(ctx context.Context, secretID string, tags map[string]string) error
+	// Delete deletes a secret immediately without a recovery window
+	Delete(ctx context.Context, secretID string) error
+	// ScheduleDeletion deletes a secret after a recovery window of 7 to 30 days and returns
+	// the deletion date. Restore cancels it
+	ScheduleDeletion(ctx context.Context, secretID string, recoveryWindowDays int) (time.Time, error)
+	// Restore cancels the scheduled deletion of a secret
+	Restore(ctx context.Context, secretID string) error
+	// List returns the secrets whose tags match every key/value in tagFilters
+	List(ctx context.Context, tagFilters map[string]string) ([]Description, error)
+	// Versions returns the versions of a secret in any order. SortVersions sorts them
+	Versions(ctx context.Context, secretID string) ([]Version, error)
+	// UpdateVersionStage attaches stage to moveToVersionID and removes it from removeFromVersionID.
+	// Either version ID may be empty. Moving AWSCURRENT moves AWSPREVIOUS to the version that
+	// had AWSCURRENT
+	UpdateVersionStage(ctx context.Context, secretID, stage, moveToVersionID, removeFromVersionID string) error
+	// ReplicateRegions replicates a secret to other regions. Existing replicas are kept
+	ReplicateRegions(ctx context.Context, secretID string, regions []string) error
+	// RemoveReplicaRegions deletes the replicas of a secret in the regions
+	RemoveReplicaRegions(ctx context.Context, secretID string, regions []string) error
+	// SetKMSKey encrypts the secret with another KMS key
+	SetKMSKey(ctx context.Context, secretID, kmsKeyID string) error
+}
+
+// checkRecoveryWindow returns a wrapped ErrInvalidRequest if the recovery window is out of range
+func checkRecoveryWindow(recoveryWindowDays int) error {
+	if recoveryWindowDays < MinRecoveryWindowDays || recoveryWindowDays > MaxRecoveryWindowDays {
+		return fmt.Errorf("%w: recovery window must be %d to %d days: %d", ErrInvalidRequest,
+			MinRecoveryWindowDays, MaxRecoveryWindowDays, recoveryWindowDays)
+	}
+	return nil
+}
+
+// Options are the settings of a secret besides its value and tags. The Secret types of the
+// resource packages embed them
+type Options struct {
+	ReplicaRegions []string    // regions the secret is replicated to. replicas aren't managed when empty
+	KmsKeyID       string      // KMS key ARN. the key isn't managed when empty
+	Store          SecretStore // defaults to Default() when nil
+}
+
+// SecretOptions returns the options so they can be changed through the Secret that embeds them
+func (o *Options) SecretOptions() *Options {
+	return o
+}
+
+// MatchesTags returns true if tags contain every key/value in filters
+func MatchesTags(tags, filters map[string]string) bool {
+	for key, value := range filters {
+		if tags[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Exists returns true if the secret exists and false if it's not found. Other Describe errors
+// are returned. A secret scheduled for deletion exists and the error wraps ErrScheduledForDeletion
+func Exists(ctx context.Context, st SecretStore, secretID string) (bool, error) {
+	description, err := st.Describe(ctx, secretID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if description.ScheduledForDeletion() {
+		return true, scheduledForDeletion(secretID, description)
+	}
+	return true, nil
+}
+
+// scheduledForDeletion returns ErrScheduledForDeletion with the secret ID and deletion date
+func scheduledForDeletion(secretID string, description Description) error {
+	return fmt.Errorf("%w: %s will be deleted on %s", ErrScheduledForDeletion, secretID,
+		description.DeletedDate.Format(time.RFC3339))
+}
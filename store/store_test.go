@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// describeStore overrides the description or the error of Describe
+type describeStore struct {
+	*MemoryStore
+	deletedDate *time.Time
+	err         error
+}
+
+func (d describeStore) Describe(ctx context.Context, secretID string) (Description, error) {
+	if d.err != nil {
+		return Description{}, d.err
+	}
+	description, err := d.MemoryStore.Describe(ctx, secretID)
+	description.DeletedDate = d.deletedDate
+	return description, err
+}
+
+func TestExists(t *testing.T) {
+	ctx := context.Background()
+	st := NewMemoryStore()
+	if _, err := st.Create(ctx, "one", "value", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	deletedDate := time.Now().Add(7 * 24 * time.Hour)
+	accessDenied := errors.New("AccessDeniedException")
+	tests := []struct {
+		name     string
+		st       SecretStore
+		secretID string
+		want     bool
+		wantErr  error
+	}{
+		{"exists", st, "one", true, nil},
+		{"not found", st, "two", false, nil},
+		{"describe error", describeStore{MemoryStore: st, err: accessDenied}, "one", false, accessDenied},
+		{"scheduled for deletion", describeStore{MemoryStore: st, deletedDate: &deletedDate}, "one", true, ErrScheduledForDeletion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Exists(ctx, tt.st, tt.secretID)
+			if got != tt.want || !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Exists() = %v, %v, want %v, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := PlanChange(ctx, describeStore{MemoryStore: st, deletedDate: &deletedDate}, "one", "value", nil, nil, "", true); !errors.Is(err, ErrScheduledForDeletion) {
+		t.Errorf("PlanChange() error = %v, want ErrScheduledForDeletion", err)
+	}
+}
@@ -1,7 +1,8 @@
 package textfile
 
 import (
-	"strings"
+	"errors"
+	"fmt"
 
 	"github.com/natemarks/secret-hoard/tools"
 	"github.com/rs/zerolog"
@@ -9,10 +10,30 @@ import (
 
 // Record is the struct of the text file record
 type Record struct {
-	ResourceType string `json:"resourceType"` // record[0] : text_file
-	Environment  string `json:"environment"`  // record[1] : dev, integration, staging, production
-	Access       string `json:"access"`       // record[2] : access type provides by the secret
-	FilePath     string `json:"filePath"`     // record[3] : /path/to/file
+	ResourceType   string `json:"resourceType"`             // text_file
+	Environment    string `json:"environment"`              // dev, integration, staging, production
+	Access         string `json:"access"`                   // access type provides by the secret
+	FilePath       string `json:"filePath"`                 // /path/to/file
+	ReplicaRegions string `json:"replicaRegions,omitempty"` // replica regions separated by ; or spaces ex. us-west-2;eu-west-1
+	KmsKeyID       string `json:"kmsKeyId,omitempty"`       // KMS key ARN to encrypt the secret with
+	Row            int    `json:"-"`                        // CSV row number, 0 when the record isn't read from a CSV file
+}
+
+// String formats the record for logs. Records only hold file paths, not secret values
+func (r Record) String() string {
+	type plain Record // plain has no String method
+	return fmt.Sprintf("%+v", plain(r))
+}
+
+// MarshalZerologObject logs the record
+func (r Record) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("resourceType", r.ResourceType).
+		Str("environment", r.Environment).
+		Str("access", r.Access).
+		Str("filePath", r.FilePath).
+		Str("replicaRegions", r.ReplicaRegions).
+		Str("kmsKeyId", r.KmsKeyID).
+		Int("row", r.Row)
 }
 
 // CSVColumns Usage output describing the CSV structure
@@ -34,26 +55,56 @@ func (r Record) Contents() (contents string, err error) {
 	return contents, err
 }
 
-// RecordsFromCSV reads a CSV file and returns a slice of Records
+// Columns are the columns of the CSV file. The header must name every column in any order
+var Columns = []tools.CSVColumn{
+	{Name: "ResourceType"},
+	{Name: "Environment"},
+	{Name: "Access"},
+	{Name: "FilePath"},
+	{Name: "ReplicaRegions", Optional: true},
+	{Name: "KmsKeyId", Optional: true},
+}
+
+// CSVValues returns the values of the record in the order of Columns
+func (r Record) CSVValues() []string {
+	return []string{r.ResourceType, r.Environment, r.Access, r.FilePath, r.ReplicaRegions, r.KmsKeyID}
+}
+
+// RecordFromCSVRow converts a CSV row read with Columns to a Record
+func RecordFromCSVRow(row tools.CSVRow) (record Record, err error) {
+	record = Record{
+		ResourceType:   row.Get("ResourceType"),
+		Environment:    row.Get("Environment"),
+		Access:         row.Get("Access"),
+		FilePath:       row.Get("FilePath"),
+		ReplicaRegions: row.Get("ReplicaRegions"),
+		KmsKeyID:       row.Get("KmsKeyId"),
+		Row:            row.Row,
+	}
+	return record, nil
+}
+
+// RecordsFromCSV reads a CSV file and returns a slice of Records. The columns are matched by
+// the header row. It returns an error for missing or unknown columns and invalid rows
 func RecordsFromCSV(csvFile string, log *zerolog.Logger) (result []Record, err error) {
-	records, err := tools.GetCSVRecordsFromFile(csvFile)
+	rows, err := tools.GetCSVRowsFromFile(csvFile, Columns)
 	if err != nil {
-		log.Error().Err(err).Msg("error reading store contents string")
+		log.Error().Err(err).Msg("error reading CSV file")
 		return result, err
 	}
 
-	for _, record := range records {
-		// skip the header row
-		if strings.ToLower(record[0]) == "resourcetype" {
+	var errs []error
+	for _, row := range rows {
+		record, err := RecordFromCSVRow(row)
+		if err != nil {
+			errs = append(errs, row.Errorf("%w", err))
 			continue
 		}
-		result = append(result, Record{
-			ResourceType: record[0],
-			Environment:  record[1],
-			Access:       record[2],
-			FilePath:     record[3],
-		})
-
+		result = append(result, record)
+	}
+	if err = errors.Join(errs...); err != nil {
+		log.Error().Err(err).Msgf("invalid rows in CSV file: %s", csvFile)
+		return nil, err
 	}
 	return result, nil
 }
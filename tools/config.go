@@ -5,20 +5,97 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/version"
 	"github.com/rs/zerolog"
 )
 
+// TagFlags collects repeated key=value flags ex. -tag=Environment=prod
+type TagFlags map[string]string
+
+// String implements flag.Value
+func (t TagFlags) String() string {
+	pairs := make([]string, 0, len(t))
+	for key, value := range t {
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value
+func (t TagFlags) Set(pair string) error {
+	key, value, ok := strings.Cut(pair, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("tag filter must be key=value: %s", pair)
+	}
+	t[key] = value
+	return nil
+}
+
+// Merge adds the values of the flags that filter a single tag to the -tag filters. tagFlags
+// maps flag names to tag keys ex. environment: Environment. Empty values are skipped. A value
+// that disagrees with the -tag value of its key is an error, unless the flag wasn't set and
+// only has its default value, which the -tag value replaces
+func (t TagFlags) Merge(flags *flag.FlagSet, tagFlags map[string]string) error {
+	set := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	names := make([]string, 0, len(tagFlags))
+	for name := range tagFlags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key, value := tagFlags[name], flags.Lookup(name).Value.String()
+		existing, ok := t[key]
+		switch {
+		case value == "":
+		case !ok:
+			t[key] = value
+		case existing != value && set[name]:
+			return fmt.Errorf("-%s=%s disagrees with -tag=%s=%s", name, value, key, existing)
+		}
+	}
+	return nil
+}
+
+// ListFlags collects the values of a repeated flag ex. -secret=a -secret=b
+type ListFlags []string
+
+// String implements flag.Value
+func (l *ListFlags) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set implements flag.Value
+func (l *ListFlags) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
 // Config is the configuration for the application
 type Config struct {
-	Overwrite bool
-	FilePath  string
-	Debug     bool
+	Overwrite          bool
+	FilePath           string
+	Debug              bool
+	Plan               bool              // print what would change without writing anything
+	ReportPath         string            // write a JSON report of every record to this path
+	Concurrency        int               // number of records processed at the same time
+	MaxAttempts        int               // attempts per Secrets Manager call. 1 disables retries
+	RetryDelay         time.Duration     // upper bound of the first retry backoff
+	RestoreDeleted     bool              // restore secrets scheduled for deletion before updating them
+	Prune              bool              // schedule the deletion of managed secrets that aren't in the file
+	RecoveryWindowDays int               // days before pruned secrets are deleted
+	AutoApprove        bool              // prune without asking for confirmation
+	ReplicaRegions     []string          // replica regions of the records without a ReplicaRegions value
+	KmsKeyID           string            // KMS key of the records without a KmsKeyId value or an environment in KmsKeys
+	KmsKeys            map[string]string // environment -> KMS key of the records without a KmsKeyId value
 }
 
 // GetLogger returns a logger for the application
@@ -32,38 +109,107 @@ func (c Config) GetLogger() (log zerolog.Logger) {
 	return log
 }
 
-// CSVType returns the type of the CSV file
-func (c Config) CSVType() (result string) {
-	records, err := GetCSVRecordsFromFile(c.FilePath)
-	if err != nil {
-		panic(err)
-	}
-	for _, record := range records {
-		// skip the header row
-		if strings.ToLower(record[0]) == "resourcetype" {
-			continue
-		}
-		return record[0]
-	}
-	panic(fmt.Errorf("no records found in CSV file: %s", c.FilePath))
+// MarshalZerologObject logs the configuration. Only the listed fields are logged so new
+// settings aren't logged until they're known to be safe
+func (c Config) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("file", c.FilePath).
+		Bool("overwrite", c.Overwrite).
+		Bool("debug", c.Debug).
+		Bool("plan", c.Plan).
+		Str("report", c.ReportPath).
+		Int("concurrency", c.Concurrency).
+		Int("maxAttempts", c.MaxAttempts).
+		Dur("retryDelay", c.RetryDelay).
+		Bool("restoreDeleted", c.RestoreDeleted).
+		Bool("prune", c.Prune).
+		Int("recoveryWindowDays", c.RecoveryWindowDays).
+		Bool("autoApprove", c.AutoApprove).
+		Strs("replicaRegions", c.ReplicaRegions).
+		Str("kmsKeyId", c.KmsKeyID).
+		Interface("kmsKeys", c.KmsKeys)
+}
+
+// RetryPolicy returns the retry policy of the Secrets Manager calls
+func (c Config) RetryPolicy() store.RetryPolicy {
+	policy := store.DefaultRetryPolicy
+	policy.MaxAttempts = c.MaxAttempts
+	policy.BaseDelay = c.RetryDelay
+	return policy
 }
 
-// GetConfig returns the configuration for the application
+// GetConfig returns the configuration of the per resource type upload applications. They
+// don't prune, so the prune flags aren't defined
 func GetConfig() (config Config, err error) {
+	return getConfig(false)
+}
+
+// GetUploadConfig returns the configuration of sh-upload with the prune flags
+func GetUploadConfig() (config Config, err error) {
+	return getConfig(true)
+}
+
+// getConfig defines and parses the upload flags. The prune flags are only defined with prune
+func getConfig(prune bool) (config Config, err error) {
 	// Define flags
 	filePtr := flag.String("file", "", "Path to the file")
 	overwritePtr := flag.Bool("overwrite", false, "Overwrite the secret value if it exists")
 	debugPtr := flag.Bool("debug", false, "Enable Debug mode")
+	reportPtr := flag.String("report", "", "Write a JSON report of every record to this path")
+	planPtr := flag.Bool("plan", false, "Print the secrets that would be created or updated without writing anything")
+	concurrencyPtr := flag.Int("concurrency", 1, "Number of records to process at the same time")
+	maxAttemptsPtr := flag.Int("max-attempts", store.DefaultRetryPolicy.MaxAttempts, "Attempts per Secrets Manager call when it's throttled or fails with a transient error")
+	restoreDeletedPtr := flag.Bool("restore-deleted", false, "Restore secrets that are scheduled for deletion and update them")
+	prunePtr, recoveryWindowPtr, autoApprovePtr := new(bool), new(int), new(bool)
+	*recoveryWindowPtr = store.DefaultRecoveryWindowDays
+	if prune {
+		flag.BoolVar(prunePtr, "prune", false, "Schedule the deletion of Source=secret-hoard secrets with a ResourceType and Environment of the file that aren't in the file")
+		flag.IntVar(recoveryWindowPtr, "recovery-window", store.DefaultRecoveryWindowDays, "Days (7 to 30) before pruned secrets are deleted. Restore them with -restore-deleted until then")
+		flag.BoolVar(autoApprovePtr, "auto-approve", false, "Prune without asking for confirmation")
+	}
+	replicaRegionsPtr := flag.String("replica-regions", "", "Regions to replicate the secrets to separated by commas ex. us-west-2,eu-west-1. A ReplicaRegions column value replaces it")
+	kmsKeyIDPtr := flag.String("kms-key-id", "", "KMS key ARN to encrypt the secrets with. A KmsKeyId column value or a -kms-keys environment replaces it")
+	kmsKeysPtr := flag.String("kms-keys", "", "YAML or JSON file of environment: KMS key ARN defaults. A KmsKeyId column value replaces them")
+	retryDelayPtr := flag.Duration("retry-delay", store.DefaultRetryPolicy.BaseDelay, "Upper bound of the first jittered retry backoff. It doubles after every attempt")
 
 	// Parse command line arguments
 	flag.Parse()
 	config.FilePath = *filePtr
 	config.Overwrite = *overwritePtr
 	config.Debug = *debugPtr
+	config.Plan = *planPtr
+	config.ReportPath = *reportPtr
+	config.Concurrency = *concurrencyPtr
+	config.MaxAttempts = *maxAttemptsPtr
+	config.RetryDelay = *retryDelayPtr
+	config.RestoreDeleted = *restoreDeletedPtr
+	config.Prune = *prunePtr
+	config.RecoveryWindowDays = *recoveryWindowPtr
+	config.AutoApprove = *autoApprovePtr
+	if config.ReplicaRegions, err = ParseReplicaRegions(*replicaRegionsPtr); err != nil {
+		return config, err
+	}
+	if config.KmsKeyID, err = ParseKMSKeyID(*kmsKeyIDPtr); err != nil {
+		return config, err
+	}
+	if *kmsKeysPtr != "" {
+		if config.KmsKeys, err = ReadKMSKeys(*kmsKeysPtr); err != nil {
+			return config, err
+		}
+	}
 
 	if !FileExists(config.FilePath) {
 		return config, fmt.Errorf("invalid file path: %s", config.FilePath)
 	}
+	if config.Concurrency < 1 {
+		return config, fmt.Errorf("invalid concurrency: %d", config.Concurrency)
+	}
+	if config.MaxAttempts < 1 {
+		return config, fmt.Errorf("invalid max attempts: %d", config.MaxAttempts)
+	}
+	if config.RecoveryWindowDays < store.MinRecoveryWindowDays || config.RecoveryWindowDays > store.MaxRecoveryWindowDays {
+		return config, fmt.Errorf("invalid recovery window: %d, it must be %d to %d days",
+			config.RecoveryWindowDays, store.MinRecoveryWindowDays, store.MaxRecoveryWindowDays)
+	}
 	return config, nil
 }
 
@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestTagFlagsMerge(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"defaults", nil, map[string]string{"Source": "secret-hoard"}, false},
+		{"flags", []string{"-environment=prod", "-source="}, map[string]string{"Environment": "prod"}, false},
+		{"tag replaces default", []string{"-tag=Source=other"}, map[string]string{"Source": "other"}, false},
+		{"same value", []string{"-tag=Environment=prod", "-environment=prod"}, map[string]string{"Source": "secret-hoard", "Environment": "prod"}, false},
+		{"disagree", []string{"-tag=Environment=prod", "-environment=dev"}, nil, true},
+		{"disagree with set default", []string{"-tag=Source=other", "-source=secret-hoard"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tags := TagFlags{}
+			flags := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			flags.String("source", "secret-hoard", "")
+			flags.String("environment", "", "")
+			flags.Var(tags, "tag", "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			err := tags.Merge(flags, map[string]string{"source": "Source", "environment": "Environment"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Merge() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !reflect.DeepEqual(map[string]string(tags), tt.want) {
+				t.Errorf("Merge() = %v, want %v", tags, tt.want)
+			}
+		})
+	}
+}
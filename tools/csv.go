@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// CSVColumn describes a column of a secrets CSV file
+type CSVColumn struct {
+	Name     string   // header name ex. ResourceType. headers are matched case-insensitively
+	Aliases  []string // other accepted header names
+	Optional bool     // the column may be left out of the header
+}
+
+// CSVRow is a data row of a CSV file
+type CSVRow struct {
+	Row    int               // row number in the file. the header is row 1
+	values map[string]string // lower case column name -> value
+}
+
+// NewCSVRow returns a CSVRow with the given column values
+func NewCSVRow(row int, values map[string]string) CSVRow {
+	result := CSVRow{Row: row, values: map[string]string{}}
+	for column, value := range values {
+		result.values[strings.ToLower(column)] = value
+	}
+	return result
+}
+
+// Get returns the value of a column or an empty string
+func (r CSVRow) Get(column string) string {
+	return r.values[strings.ToLower(column)]
+}
+
+// Has returns true if the row has the column
+func (r CSVRow) Has(column string) bool {
+	_, ok := r.values[strings.ToLower(column)]
+	return ok
+}
+
+// Errorf returns an error prefixed with the row number. It wraps errors like fmt.Errorf
+func (r CSVRow) Errorf(format string, a ...any) error {
+	return fmt.Errorf("row %d: %w", r.Row, fmt.Errorf(format, a...))
+}
+
+// ReadCSVFile reads a CSV file whose first row is the header. The header must have a
+// ResourceType column. Repeated header rows are skipped
+func ReadCSVFile(csvFile string) (header []string, rows []CSVRow, err error) {
+	file, err := os.Open(csvFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	reader := csv.NewReader(file)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		row, _ := reader.FieldPos(0)
+		if header == nil {
+			header = record
+			if !containsFold(header, "ResourceType") {
+				return nil, nil, fmt.Errorf("row %d: the first row must be the header with a ResourceType column, got %s",
+					row, strings.Join(record, ","))
+			}
+			continue
+		}
+		values := map[string]string{}
+		for i, value := range record {
+			values[strings.ToLower(header[i])] = value
+		}
+		csvRow := CSVRow{Row: row, values: values}
+		// skip repeated header rows
+		if strings.EqualFold(csvRow.Get("ResourceType"), "ResourceType") {
+			continue
+		}
+		rows = append(rows, csvRow)
+	}
+	if header == nil {
+		return nil, nil, fmt.Errorf("empty CSV file: %s", csvFile)
+	}
+	return header, rows, nil
+}
+
+// GetCSVRowsFromFile reads a CSV file and checks the header against columns. Aliases are
+// replaced with the column names so CSVRow.Get always uses the column name. It returns an
+// error for unknown, duplicate or missing columns
+func GetCSVRowsFromFile(csvFile string, columns []CSVColumn) (rows []CSVRow, err error) {
+	header, rows, err := ReadCSVFile(csvFile)
+	if err != nil {
+		return nil, err
+	}
+	if err = CheckCSVHeader(header, columns); err != nil {
+		return nil, fmt.Errorf("row 1: %w", err)
+	}
+	for i, row := range rows {
+		rows[i] = row.Select(columns)
+	}
+	return rows, nil
+}
+
+// CheckCSVHeader returns an error for unknown, duplicate or missing columns
+func CheckCSVHeader(header []string, columns []CSVColumn) error {
+	return errors.Join(UnknownCSVColumns(header, columns), MissingCSVColumns(header, columns))
+}
+
+// UnknownCSVColumns returns an error for the header names that don't match any column
+// and for columns that are in the header more than once
+func UnknownCSVColumns(header []string, columns []CSVColumn) error {
+	var errs []error
+	found := map[string]bool{}
+	for _, name := range header {
+		column, ok := findCSVColumn(name, columns)
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("unknown column %q", name))
+		case found[column.Name]:
+			errs = append(errs, fmt.Errorf("duplicate column %q", name))
+		default:
+			found[column.Name] = true
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// MissingCSVColumns returns an error for the columns that aren't optional and aren't in the header
+func MissingCSVColumns(header []string, columns []CSVColumn) error {
+	var errs []error
+	for _, column := range columns {
+		if column.Optional {
+			continue
+		}
+		found := false
+		for _, name := range header {
+			if column.Matches(name) {
+				found = true
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("missing column %q", column.Name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Matches returns true if name is the column name or one of its aliases ignoring case
+func (c CSVColumn) Matches(name string) bool {
+	return strings.EqualFold(c.Name, name) || containsFold(c.Aliases, name)
+}
+
+// findCSVColumn returns the column matching name
+func findCSVColumn(name string, columns []CSVColumn) (CSVColumn, bool) {
+	for _, column := range columns {
+		if column.Matches(name) {
+			return column, true
+		}
+	}
+	return CSVColumn{}, false
+}
+
+// Select returns a copy of the row with only the columns. Aliases are replaced with the
+// column names
+func (r CSVRow) Select(columns []CSVColumn) CSVRow {
+	result := CSVRow{Row: r.Row, values: map[string]string{}}
+	for name, value := range r.values {
+		if column, ok := findCSVColumn(name, columns); ok {
+			result.values[strings.ToLower(column.Name)] = value
+		}
+	}
+	return result
+}
+
+// containsFold returns true if values contains value ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testColumns = []CSVColumn{
+	{Name: "ResourceType"},
+	{Name: "Environment"},
+	{Name: "JSONFilePath", Aliases: []string{"File"}},
+	{Name: "KmsKeyId", Optional: true},
+}
+
+// writeCSV writes contents to a CSV file in a temp directory
+func writeCSV(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.csv")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestGetCSVRowsFromFile(t *testing.T) {
+	path := writeCSV(t, "environment,File,resourcetype\ntestenv,a.json,jsondoc\n\nEnvironment,File,ResourceType\nprod,\"b\nc.json\",jsondoc\n")
+	rows, err := GetCSVRowsFromFile(path, testColumns)
+	if err != nil {
+		t.Fatalf("GetCSVRowsFromFile() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("GetCSVRowsFromFile() = %d rows, want 2", len(rows))
+	}
+	if rows[0].Row != 2 || rows[0].Get("ResourceType") != "jsondoc" || rows[0].Get("JSONFilePath") != "a.json" {
+		t.Errorf("rows[0] = %+v", rows[0])
+	}
+	if rows[1].Row != 5 || rows[1].Get("environment") != "prod" || rows[1].Has("KmsKeyId") {
+		t.Errorf("rows[1] = %+v", rows[1])
+	}
+}
+
+func TestGetCSVRowsFromFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{"no header", "jsondoc,testenv,a.json\n", "row 1: the first row must be the header"},
+		{"missing column", "ResourceType,Environment\njsondoc,testenv\n", `missing column "JSONFilePath"`},
+		{"unknown column", "ResourceType,Environment,File,Colour\njsondoc,testenv,a.json,red\n", `unknown column "Colour"`},
+		{"duplicate column", "ResourceType,Environment,File,JSONFilePath\njsondoc,testenv,a.json,b.json\n", `duplicate column "JSONFilePath"`},
+		{"short row", "ResourceType,Environment,File\njsondoc,testenv\n", "line 2"},
+		{"empty", "", "empty CSV file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := GetCSVRowsFromFile(writeCSV(t, tt.contents), testColumns)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("GetCSVRowsFromFile() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -2,12 +2,10 @@ package tools
 
 import (
 	"crypto/sha256"
-	"encoding/csv"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
-	"strings"
 )
 
 // FileExists checks if a file exists
@@ -40,6 +38,11 @@ func WriteStringToFile(content string, filename string) error {
 	return nil
 }
 
+// WriteSecretFile writes a secret value to a file only the owner can read
+func WriteSecretFile(content string, filename string) error {
+	return os.WriteFile(filename, []byte(content), 0o600)
+}
+
 // GetSHA256Sum returns the SHA256 sum of a file
 func GetSHA256Sum(filePath string) (string, error) {
 	file, err := os.Open(filePath)
@@ -64,22 +67,6 @@ func GetSHA256Sum(filePath string) (string, error) {
 	return sha256sum, nil
 }
 
-// GetCSVRecordsFromFile reads a CSV file and returns a slice of string slices
-// This will include the header row which can be identified by the first element
-// (record[0]) being "ResourceType"
-func GetCSVRecordsFromFile(csvFile string) (records [][]string, err error) {
-	csvContents, err := ReadFileToString(csvFile)
-	if err != nil {
-		return nil, err
-	}
-	reader := csv.NewReader(strings.NewReader(csvContents))
-	records, err = reader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
-	return records, nil
-}
-
 // CheckSha256Sum checks if the SHA256 sum of a file matches the expected value
 func CheckSha256Sum(filePath string, expected string) (err error) {
 	sha256Sum, err := GetSHA256Sum(filePath)
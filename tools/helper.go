@@ -3,76 +3,110 @@ package tools
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
 
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/version"
 	"github.com/rs/zerolog"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
-	smtypes "github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
 )
 
-// ConvertMapToTags Convert a map to a list of tags
-func ConvertMapToTags(tags map[string]string) []smtypes.Tag {
-	var tagList []smtypes.Tag
-	for key, value := range tags {
-		tag := smtypes.Tag{
-			Key:   aws.String(key),
-			Value: aws.String(value),
-		}
-		tagList = append(tagList, tag)
-	}
-	return tagList
-}
-
 // DeleteSecrets deletes the given secrets
-func DeleteSecrets(secretIDs []string) {
+func DeleteSecrets(st store.SecretStore, secretIDs []string) {
 	ctx := context.Background()
-	cfg, _ := config.LoadDefaultConfig(ctx)
+	for _, secretID := range secretIDs {
+		_ = st.Delete(ctx, secretID)
+	}
+}
 
-	client := secretsmanager.NewFromConfig(cfg)
+// GetSecretValue retrieves the value of a secret. Transient errors are retried with
+// store.DefaultRetryPolicy
+func GetSecretValue(st store.SecretStore, secretID string) (string, error) {
+	return store.NewRetryStore(st, store.DefaultRetryPolicy).Get(context.TODO(), secretID)
+}
 
-	for _, secretID := range secretIDs {
-		_, _ = client.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
-			SecretId:                   &secretID,
-			ForceDeleteWithoutRecovery: aws.Bool(true),
-		})
+// GetSecretVersionValue retrieves the value of the version of a secret with versionID or
+// stage. Both empty is AWSCURRENT. Transient errors are retried with store.DefaultRetryPolicy
+func GetSecretVersionValue(st store.SecretStore, secretID, versionID, stage string) (string, error) {
+	return store.NewRetryStore(st, store.DefaultRetryPolicy).GetVersion(context.TODO(), secretID, versionID, stage)
+}
 
-	}
+// GetSecretVersions returns the versions of a secret and their stages, oldest first.
+// Transient errors are retried with store.DefaultRetryPolicy
+func GetSecretVersions(st store.SecretStore, secretID string) ([]store.Version, error) {
+	versions, err := store.NewRetryStore(st, store.DefaultRetryPolicy).Versions(context.TODO(), secretID)
+	store.SortVersions(versions)
+	return versions, err
 }
 
-// GetSecretValue retrieves the value of a secret
-func GetSecretValue(secretID string) (string, error) {
-	// Load AWS SDK configuration
-	cfg, err := config.LoadDefaultConfig(context.TODO())
+// MoveVersionStage moves stage to versionID and removes it from the version that has it.
+// Moving AWSCURRENT makes the version that had it AWSPREVIOUS. It returns the versions
+// before and after the move, oldest first. Nothing changes if versionID already has the stage
+func MoveVersionStage(st store.SecretStore, secretID, stage, versionID string) (before, after []store.Version, err error) {
+	retryStore := store.NewRetryStore(st, store.DefaultRetryPolicy)
+	before, err = GetSecretVersions(st, secretID)
 	if err != nil {
-		return "", err
+		return nil, nil, err
 	}
-
-	// Create Secrets Manager client
-	client := secretsmanager.NewFromConfig(cfg)
-
-	// Prepare input parameters
-	input := &secretsmanager.GetSecretValueInput{
-		SecretId: &secretID,
+	holder, found := "", false
+	for _, version := range before {
+		if version.VersionID == versionID {
+			found = true
+		}
+		for _, versionStage := range version.Stages {
+			if versionStage == stage {
+				holder = version.VersionID
+			}
+		}
 	}
-
-	// Retrieve secret value
-	result, err := client.GetSecretValue(context.TODO(), input)
+	if !found {
+		return before, nil, fmt.Errorf("%w: version %s of %s", store.ErrNotFound, versionID, secretID)
+	}
+	if holder == versionID {
+		return before, before, nil
+	}
+	err = retryStore.UpdateVersionStage(context.TODO(), secretID, stage, versionID, holder)
 	if err != nil {
-		return "", err
+		return before, nil, err
 	}
+	after, err = GetSecretVersions(st, secretID)
+	return before, after, err
+}
 
-	// Check if secret value is present
-	if result.SecretString == nil {
-		return "", fmt.Errorf("secret value is nil")
+// RollbackSecret moves AWSCURRENT to the AWSPREVIOUS version so the two versions swap stages
+func RollbackSecret(st store.SecretStore, secretID string) (before, after []store.Version, err error) {
+	versions, err := GetSecretVersions(st, secretID)
+	if err != nil {
+		return nil, nil, err
 	}
+	for _, version := range versions {
+		for _, stage := range version.Stages {
+			if stage == store.StagePrevious {
+				return MoveVersionStage(st, secretID, store.StageCurrent, version.VersionID)
+			}
+		}
+	}
+	return versions, nil, fmt.Errorf("%w: %s has no %s version", store.ErrInvalidRequest, secretID, store.StagePrevious)
+}
 
-	// Return the secret value
-	return *result.SecretString, nil
+// WriteVersions writes a table of the version IDs, creation dates and stages
+func WriteVersions(w io.Writer, versions []store.Version) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VERSION ID\tCREATED\tSTAGES")
+	for _, version := range versions {
+		created := ""
+		if version.CreatedDate != nil {
+			created = version.CreatedDate.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\n", version.VersionID, created, strings.Join(version.Stages, ","))
+	}
+	_ = tw.Flush()
 }
 
 // GetResourceTypeFromSecretID returns the resource type from a secret ID
@@ -84,9 +118,53 @@ func GetResourceTypeFromSecretID(secretID string) (result string, err error) {
 	return parts[0], nil
 }
 
+// TestLogger returns a debug logger for tests. It doesn't look up the AWS account so
+// tests using a fake store run without credentials
 func TestLogger() (log zerolog.Logger) {
 	log = zerolog.New(os.Stdout).With().Str("version", version.Version).Timestamp().Logger()
-	log = log.With().Str("aws_account_number", GetAWSAccountNumber()).Logger()
 	log = log.Level(zerolog.DebugLevel)
 	return log
 }
+
+// SecretFileName returns a file name for the files of a secret: the secret ID with the slashes
+// replaced with underscores
+func SecretFileName(secretID string) string {
+	return strings.ReplaceAll(secretID, "/", "_")
+}
+
+// RequireTags returns an error naming the tags that are missing or empty
+func RequireTags(tags map[string]string, names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if tags[name] == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tags: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// replicaRegionPattern matches AWS region names ex. us-west-2
+var replicaRegionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-\d+$`)
+
+// ParseReplicaRegions splits regions separated by commas, semicolons or spaces. It returns
+// nil for an empty value and an error for values that aren't region names
+func ParseReplicaRegions(value string) ([]string, error) {
+	var regions []string
+	seen := map[string]bool{}
+	for _, region := range strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ';' || unicode.IsSpace(r)
+	}) {
+		if !replicaRegionPattern.MatchString(region) {
+			return nil, fmt.Errorf("invalid replica region: %q", region)
+		}
+		if !seen[region] {
+			seen[region] = true
+			regions = append(regions, region)
+		}
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
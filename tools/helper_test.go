@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/natemarks/secret-hoard/store"
+)
+
+// stagesByVersion returns the stages of every version
+func stagesByVersion(versions []store.Version) map[string]string {
+	result := map[string]string{}
+	for _, version := range versions {
+		result[version.VersionID] = strings.Join(version.Stages, ",")
+	}
+	return result
+}
+
+func TestMoveVersionStage(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	secretID := "text_file/testenv/my_file_type"
+	first, err := st.Create(ctx, secretID, "one", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := st.Put(ctx, secretID, "two")
+	if err != nil {
+		t.Fatal(err)
+	}
+	third, err := st.Put(ctx, secretID, "three")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before, after, err := RollbackSecret(st, secretID)
+	if err != nil {
+		t.Fatalf("RollbackSecret() error = %v", err)
+	}
+	if got := stagesByVersion(before); got[third] != store.StageCurrent || got[second] != store.StagePrevious {
+		t.Errorf("RollbackSecret() before = %v", got)
+	}
+	if got := stagesByVersion(after); got[second] != store.StageCurrent || got[third] != store.StagePrevious {
+		t.Errorf("RollbackSecret() after = %v", got)
+	}
+
+	// move AWSCURRENT to any version
+	if _, after, err = MoveVersionStage(st, secretID, store.StageCurrent, first); err != nil {
+		t.Fatalf("MoveVersionStage() error = %v", err)
+	}
+	if value, err := GetSecretValue(st, secretID); err != nil || value != "one" {
+		t.Errorf("GetSecretValue() = %q, %v, want \"one\"", value, err)
+	}
+	if got := stagesByVersion(after); got[first] != store.StageCurrent || got[second] != store.StagePrevious || got[third] != "" {
+		t.Errorf("MoveVersionStage() after = %v", got)
+	}
+
+	// the version already has the stage
+	if before, after, err = MoveVersionStage(st, secretID, store.StageCurrent, first); err != nil || len(before) != len(after) {
+		t.Errorf("MoveVersionStage() = %v, %v, %v", before, after, err)
+	}
+	if _, _, err = MoveVersionStage(st, secretID, store.StageCurrent, "missing"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("MoveVersionStage() error = %v, want ErrNotFound", err)
+	}
+
+	var buf bytes.Buffer
+	WriteVersions(&buf, after)
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 || !strings.HasPrefix(lines[0], "VERSION ID") {
+		t.Errorf("WriteVersions() = %s", buf.String())
+	}
+}
+
+// reversedStore lists the versions newest first like Secrets Manager may
+type reversedStore struct {
+	*store.MemoryStore
+}
+
+// Versions returns the versions newest first
+func (r reversedStore) Versions(ctx context.Context, secretID string) ([]store.Version, error) {
+	versions, err := r.MemoryStore.Versions(ctx, secretID)
+	slices.Reverse(versions)
+	return versions, err
+}
+
+func TestGetSecretVersionsOrder(t *testing.T) {
+	ctx := context.Background()
+	st := reversedStore{store.NewMemoryStore()}
+	first, err := st.Create(ctx, "one", "first", nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	second, err := st.Put(ctx, "one", "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := GetSecretVersions(st, "one")
+	if err != nil || len(versions) != 2 || versions[0].VersionID != first || versions[1].VersionID != second {
+		t.Errorf("GetSecretVersions() = %+v, %v, want %s then %s", versions, err, first, second)
+	}
+}
+
+func TestRollbackSecretWithoutPrevious(t *testing.T) {
+	st := store.NewMemoryStore()
+	if _, err := st.Create(context.Background(), "one", "value", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := RollbackSecret(st, "one"); !errors.Is(err, store.ErrInvalidRequest) {
+		t.Errorf("RollbackSecret() error = %v, want ErrInvalidRequest", err)
+	}
+}
+
+func TestParseReplicaRegions(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"us-west-2", []string{"us-west-2"}, false},
+		{"us-west-2;eu-west-1 us-west-2", []string{"eu-west-1", "us-west-2"}, false},
+		{"us-gov-west-1, ap-southeast-2", []string{"ap-southeast-2", "us-gov-west-1"}, false},
+		{"us-west", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseReplicaRegions(tt.value)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseReplicaRegions(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
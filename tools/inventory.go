@@ -0,0 +1,129 @@
+package tools
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/natemarks/secret-hoard/store"
+)
+
+// Inventory output formats
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+)
+
+// InventoryFormats are the formats WriteInventory supports
+var InventoryFormats = []string{FormatTable, FormatJSON, FormatCSV}
+
+// InventoryColumns are the columns of the table and CSV inventory formats
+var InventoryColumns = []string{"SecretID", "LastChangedDate", "VersionCount", "Tags", "Replicas"}
+
+// InventoryEntry describes a listed secret
+type InventoryEntry struct {
+	SecretID        string            `json:"secretId"`
+	Tags            map[string]string `json:"tags"`
+	LastChangedDate *time.Time        `json:"lastChangedDate,omitempty"`
+	VersionCount    int               `json:"versionCount"`
+	Replicas        []store.Replica   `json:"replicas,omitempty"`
+}
+
+// ListSecrets returns the secrets whose tags match every key/value in tagFilters sorted by
+// secret ID. The versions of every secret are listed to count them and every secret is
+// described for its replication status. Transient errors are retried with
+// store.DefaultRetryPolicy
+func ListSecrets(st store.SecretStore, tagFilters map[string]string) ([]InventoryEntry, error) {
+	ctx := context.TODO()
+	retryStore := store.NewRetryStore(st, store.DefaultRetryPolicy)
+	descriptions, err := retryStore.List(ctx, tagFilters)
+	if err != nil {
+		return nil, err
+	}
+	entries := []InventoryEntry{}
+	for _, description := range descriptions {
+		versions, err := retryStore.Versions(ctx, description.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error listing versions of %s: %w", description.Name, err)
+		}
+		// List doesn't return the replicas
+		described, err := retryStore.Describe(ctx, description.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error describing %s: %w", description.Name, err)
+		}
+		entries = append(entries, InventoryEntry{
+			SecretID:        description.Name,
+			Tags:            description.Tags,
+			LastChangedDate: description.LastChangedDate,
+			VersionCount:    len(versions),
+			Replicas:        described.Replicas,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].SecretID < entries[j].SecretID })
+	return entries, nil
+}
+
+// formatTags returns the tags as key=value pairs sorted by key
+func formatTags(tags map[string]string) string {
+	pairs := make([]string, 0, len(tags))
+	for key, value := range tags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// formatReplicas returns the replicas as region=status pairs
+func formatReplicas(replicas []store.Replica) string {
+	pairs := make([]string, len(replicas))
+	for i, replica := range replicas {
+		pairs[i] = replica.Region + "=" + replica.Status
+	}
+	return strings.Join(pairs, ",")
+}
+
+// columns returns the values of the InventoryColumns
+func (e InventoryEntry) columns() []string {
+	lastChanged := ""
+	if e.LastChangedDate != nil {
+		lastChanged = e.LastChangedDate.UTC().Format(time.RFC3339)
+	}
+	return []string{e.SecretID, lastChanged, strconv.Itoa(e.VersionCount), formatTags(e.Tags), formatReplicas(e.Replicas)}
+}
+
+// WriteInventory writes the entries as a table, a JSON list or a CSV file with a header
+func WriteInventory(w io.Writer, entries []InventoryEntry, format string) error {
+	switch format {
+	case FormatTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(tw, "SECRET ID\tLAST CHANGED\tVERSIONS\tTAGS\tREPLICAS")
+		for _, entry := range entries {
+			_, _ = fmt.Fprintln(tw, strings.Join(entry.columns(), "\t"))
+		}
+		return tw.Flush()
+	case FormatJSON:
+		if entries == nil {
+			entries = []InventoryEntry{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		_ = writer.Write(InventoryColumns)
+		for _, entry := range entries {
+			_ = writer.Write(entry.columns())
+		}
+		writer.Flush()
+		return writer.Error()
+	}
+	return fmt.Errorf("unsupported format %q. use one of: %s", format, strings.Join(InventoryFormats, ", "))
+}
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/store"
+)
+
+func TestListSecrets(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemoryStore()
+	managed := map[string]string{"Source": "secret-hoard", "ResourceType": "text_file", "Environment": "testenv", "Access": "one"}
+	if _, err := st.Create(ctx, "text_file/testenv/one", "one", managed, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Put(ctx, "text_file/testenv/one", "two"); err != nil {
+		t.Fatal(err)
+	}
+	other := map[string]string{"Source": "secret-hoard", "ResourceType": "jsondoc", "Environment": "prod", "Access": "two"}
+	if _, err := st.Create(ctx, "jsondoc/prod/two", "{}", other, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Create(ctx, "unmanaged", "value", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := ListSecrets(st, map[string]string{"Source": "secret-hoard"})
+	if err != nil {
+		t.Fatalf("ListSecrets() error = %v", err)
+	}
+	if len(entries) != 2 || entries[0].SecretID != "jsondoc/prod/two" || entries[1].SecretID != "text_file/testenv/one" {
+		t.Fatalf("ListSecrets() = %+v", entries)
+	}
+	if entries[1].VersionCount != 2 || entries[1].LastChangedDate == nil || entries[1].Tags["Access"] != "one" {
+		t.Errorf("ListSecrets() entry = %+v", entries[1])
+	}
+	if entries, err = ListSecrets(st, map[string]string{"Source": "secret-hoard", "Environment": "prod"}); err != nil || len(entries) != 1 {
+		t.Errorf("ListSecrets(Environment=prod) = %+v, %v", entries, err)
+	}
+
+	var buf bytes.Buffer
+	if err = WriteInventory(&buf, entries, FormatTable); err != nil || !strings.Contains(buf.String(), "jsondoc/prod/two") {
+		t.Errorf("WriteInventory(table) = %s, %v", buf.String(), err)
+	}
+	buf.Reset()
+	var listed []InventoryEntry
+	if err = WriteInventory(&buf, entries, FormatJSON); err != nil {
+		t.Fatalf("WriteInventory(json) error = %v", err)
+	}
+	if err = json.Unmarshal(buf.Bytes(), &listed); err != nil || len(listed) != 1 || listed[0].VersionCount != 1 {
+		t.Errorf("WriteInventory(json) = %s, %v", buf.String(), err)
+	}
+	buf.Reset()
+	if err = WriteInventory(&buf, entries, FormatCSV); err != nil {
+		t.Fatalf("WriteInventory(csv) error = %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(rows) != 2 || rows[1][0] != "jsondoc/prod/two" {
+		t.Errorf("WriteInventory(csv) = %v, %v", rows, err)
+	}
+	if want := "Access=two,Environment=prod,ResourceType=jsondoc,Source=secret-hoard"; rows[1][3] != want {
+		t.Errorf("WriteInventory(csv) tags = %q, want %q", rows[1][3], want)
+	}
+	if err = WriteInventory(&buf, entries, "xml"); err == nil {
+		t.Error("WriteInventory(xml) error = nil")
+	}
+}
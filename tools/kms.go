@@ -0,0 +1,44 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kmsKeyARNPattern matches KMS key ARNs ex. arn:aws:kms:us-east-1:111122223333:key/1234abcd
+var kmsKeyARNPattern = regexp.MustCompile(`^arn:aws[a-z-]*:kms:[a-z0-9-]+:\d{12}:key/[A-Za-z0-9-]+$`)
+
+// ParseKMSKeyID returns the KMS key ARN or an error for values that aren't key ARNs. A key ID
+// or alias can't be compared with the key a secret is described with. An empty value is
+// returned as is
+func ParseKMSKeyID(value string) (string, error) {
+	if value != "" && !kmsKeyARNPattern.MatchString(value) {
+		return "", fmt.Errorf("invalid KMS key: %q, it must be a key ARN ex. arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab", value)
+	}
+	return value, nil
+}
+
+// ReadKMSKeys reads a YAML or JSON file that maps environments to the KMS key ARN of their
+// secrets ex. production: arn:aws:kms:us-east-1:111122223333:key/1234abcd
+func ReadKMSKeys(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keys map[string]string
+	if err = yaml.Unmarshal(content, &keys); err != nil {
+		return nil, fmt.Errorf("invalid KMS keys file %s: %w", path, err)
+	}
+	for environment, kmsKeyID := range keys {
+		if environment == "" || kmsKeyID == "" {
+			return nil, fmt.Errorf("invalid KMS keys file %s: empty environment or key ID: %q: %q", path, environment, kmsKeyID)
+		}
+		if _, err = ParseKMSKeyID(kmsKeyID); err != nil {
+			return nil, fmt.Errorf("invalid KMS keys file %s: environment %s: %w", path, environment, err)
+		}
+	}
+	return keys, nil
+}
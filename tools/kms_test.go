@@ -0,0 +1,23 @@
+package tools
+
+import "testing"
+
+func TestParseKMSKeyID(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"", false},
+		{"arn:aws:kms:us-east-1:111122223333:key/1234abcd-12ab-34cd-56ef-1234567890ab", false},
+		{"arn:aws-us-gov:kms:us-gov-west-1:111122223333:key/1234abcd", false},
+		{"1234abcd-12ab-34cd-56ef-1234567890ab", true},
+		{"alias/production", true},
+		{"arn:aws:kms:us-east-1:111122223333:alias/production", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseKMSKeyID(tt.value)
+		if (err != nil) != tt.wantErr || (err == nil && got != tt.value) {
+			t.Errorf("ParseKMSKeyID(%q) = %q, %v, want error %v", tt.value, got, err, tt.wantErr)
+		}
+	}
+}
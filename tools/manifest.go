@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestEntry is a record of a YAML or JSON manifest
+type ManifestEntry struct {
+	Line         int    // line number of the record in the file
+	ResourceType string // value of the resourceType field
+	data         []byte // the record as a JSON object
+}
+
+// IsManifestFile returns true if the file extension is .yaml, .yml or .json
+func IsManifestFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
+
+// Errorf returns an error prefixed with the line number of the entry
+func (e ManifestEntry) Errorf(format string, a ...any) error {
+	return fmt.Errorf("line %d: %w", e.Line, fmt.Errorf(format, a...))
+}
+
+// Decode decodes the entry into a Record struct using its json tags. Fields that don't
+// belong to the record are an error
+func (e ManifestEntry) Decode(record any) error {
+	decoder := json.NewDecoder(bytes.NewReader(e.data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(record)
+}
+
+// ReadManifestFile reads a YAML or JSON manifest: a list of records with the json field names
+// of the resource type's Record struct. JSON is read as YAML, so both formats report line numbers
+func ReadManifestFile(path string) (entries []ManifestEntry, err error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var document yaml.Node
+	if err = yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, errors.New("empty manifest")
+	}
+	list := document.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %d: manifest must be a list of records", list.Line)
+	}
+	for _, node := range list.Content {
+		entry := ManifestEntry{Line: node.Line}
+		if node.Kind != yaml.MappingNode {
+			return nil, entry.Errorf("record must be a mapping")
+		}
+		var fields map[string]any
+		if err = node.Decode(&fields); err != nil {
+			return nil, entry.Errorf("%w", err)
+		}
+		if entry.ResourceType, err = manifestResourceType(fields); err != nil {
+			return nil, entry.Errorf("%w", err)
+		}
+		if entry.data, err = json.Marshal(fields); err != nil {
+			return nil, entry.Errorf("%w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// manifestResourceType returns the resourceType field of a record
+func manifestResourceType(fields map[string]any) (string, error) {
+	for key, value := range fields {
+		if !strings.EqualFold(key, "resourceType") {
+			continue
+		}
+		resourceType, ok := value.(string)
+		if !ok || resourceType == "" {
+			return "", errors.New("resourceType must be a string")
+		}
+		return resourceType, nil
+	}
+	return "", errors.New("missing resourceType")
+}
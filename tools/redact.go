@@ -0,0 +1,12 @@
+package tools
+
+// Redacted replaces secret values in log output
+const Redacted = "********"
+
+// Mask returns Redacted for a non-empty secret value so logs show whether it was set
+func Mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return Redacted
+}
@@ -0,0 +1,226 @@
+package uploader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/rs/zerolog"
+)
+
+// DiffEntry is the drift of one secret between the file and the secret store
+type DiffEntry struct {
+	Row                  int               // CSV row number or manifest line number. 0 for extra secrets
+	SecretID             string            // empty if the record couldn't be converted to a secret
+	Missing              bool              // the secret is in the file but not in the store
+	Extra                bool              // a managed secret in the environment that isn't in the file
+	ScheduledForDeletion bool              // the secret is in the file but scheduled for deletion
+	Fields               []string          // value fields that differ prefixed with +, ~ or -
+	Tags                 map[string]string // tags that are missing or different in the store
+	Replicas             []string          // replica regions that are missing (+) or extra (-) in the store
+	KmsKeyID             string            // KMS key of the file when the stored secret uses another one
+	Err                  error
+}
+
+// Drifted returns true if the stored secret doesn't match the file
+func (d DiffEntry) Drifted() bool {
+	return d.Missing || d.Extra || d.ScheduledForDeletion || len(d.Fields) > 0 || len(d.Tags) > 0 || len(d.Replicas) > 0 || d.KmsKeyID != ""
+}
+
+// status describes the drift of the entry
+func (d DiffEntry) status() string {
+	switch {
+	case d.Err != nil:
+		return "error"
+	case d.Missing:
+		return "missing"
+	case d.Extra:
+		return "extra"
+	case d.ScheduledForDeletion:
+		return "scheduled for deletion"
+	}
+	var result []string
+	if len(d.Fields) > 0 {
+		result = append(result, "value differs")
+	}
+	if len(d.Tags) > 0 {
+		result = append(result, "tags differ")
+	}
+	if len(d.Replicas) > 0 {
+		result = append(result, "replicas differ")
+	}
+	if d.KmsKeyID != "" {
+		result = append(result, "kms key differs")
+	}
+	if len(result) == 0 {
+		return "in sync"
+	}
+	return strings.Join(result, ", ")
+}
+
+// Diff is the drift of every secret in the file and of the extra secrets
+type Diff []DiffEntry
+
+// Drifted returns the number of secrets that don't match the file
+func (d Diff) Drifted() (count int) {
+	for _, entry := range d {
+		if entry.Err == nil && entry.Drifted() {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of records that couldn't be compared
+func (d Diff) Failed() (count int) {
+	for _, entry := range d {
+		if entry.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// diffSymbols prefix each secret in the diff
+var diffSymbols = map[string]string{
+	"error":                  "x",
+	"missing":                "+",
+	"extra":                  "-",
+	"scheduled for deletion": "^",
+	"in sync":                "=",
+}
+
+// WriteDiff writes the drift of every secret. Secret values are masked: only the names of
+// the fields that differ are written
+func WriteDiff(w io.Writer, diff Diff) {
+	counts := map[string]int{}
+	for _, entry := range diff {
+		status := entry.status()
+		symbol, ok := diffSymbols[status]
+		if !ok {
+			symbol = "~"
+		}
+		name := entry.SecretID
+		if name == "" {
+			name = fmt.Sprintf("row %d", entry.Row)
+		}
+		_, _ = fmt.Fprintf(w, "  %s %s (%s)\n", symbol, name, status)
+		if entry.Err != nil {
+			_, _ = fmt.Fprintf(w, "      %s\n", entry.Err)
+			counts["failed"]++
+			continue
+		}
+		for _, field := range entry.Fields {
+			_, _ = fmt.Fprintf(w, "      %s = (sensitive)\n", field)
+		}
+		var keys []string
+		for key := range entry.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(w, "      tag %s = %q\n", key, entry.Tags[key])
+		}
+		for _, replica := range entry.Replicas {
+			_, _ = fmt.Fprintf(w, "      replica %s\n", replica)
+		}
+		if entry.KmsKeyID != "" {
+			_, _ = fmt.Fprintf(w, "      kms key = %s\n", entry.KmsKeyID)
+		}
+		switch {
+		case entry.Missing:
+			counts["missing"]++
+		case entry.Extra:
+			counts["extra"]++
+		case entry.ScheduledForDeletion:
+			counts["deleted"]++
+		case !entry.Drifted():
+			counts["sync"]++
+		}
+		if len(entry.Fields) > 0 {
+			counts["values"]++
+		}
+		if len(entry.Tags) > 0 {
+			counts["tags"]++
+		}
+		if len(entry.Replicas) > 0 {
+			counts["replicas"]++
+		}
+		if entry.KmsKeyID != "" {
+			counts["kms"]++
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\nDiff: %d missing, %d extra, %d value differs, %d tags differ, %d replicas differ, %d kms key differs, %d scheduled for deletion, %d in sync, %d failed.\n",
+		counts["missing"], counts["extra"], counts["values"], counts["tags"], counts["replicas"], counts["kms"], counts["deleted"], counts["sync"], counts["failed"])
+}
+
+// Diff compares every record of the file with the stored secret without writing anything.
+// Secrets tagged Source=secret-hoard in the environments of the file that aren't in the file
+// are reported as extra
+func (m ManifestProcessor) Diff(cfg tools.Config, log *zerolog.Logger) (Diff, error) {
+	results, secrets, _, err := m.readSecrets(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(Diff, len(results))
+	forEach(cfg.Concurrency, len(secrets), func(i int) {
+		entry := &diff[i]
+		entry.Row = results[i].Row
+		if entry.Err = results[i].Err; entry.Err != nil {
+			return
+		}
+		secret := secrets[i]
+		entry.SecretID = secret.SecretID()
+		change, err := secret.Plan(true, log)
+		switch {
+		case errors.Is(err, store.ErrScheduledForDeletion):
+			entry.ScheduledForDeletion = true
+		case err != nil:
+			log.Error().Err(err).Msgf("error comparing secret: %s", secret.SecretID())
+			entry.Err = err
+		case change.Action == store.ActionCreate:
+			entry.Missing = true
+		case change.Action == store.ActionUpdate:
+			entry.Fields = change.Fields
+			if len(change.Tags) > 0 {
+				entry.Tags = change.Tags
+			}
+			entry.Replicas = change.Replicas
+			entry.KmsKeyID = change.KmsKeyID
+		}
+	})
+
+	// managed secrets in the environments of the file
+	inFile := map[string]bool{}
+	environments := map[string]bool{}
+	for _, secret := range secrets {
+		if secret != nil {
+			inFile[secret.SecretID()] = true
+			environments[secret.Tags()["Environment"]] = true
+		}
+	}
+	var names []string
+	for environment := range environments {
+		names = append(names, environment)
+	}
+	sort.Strings(names)
+	st := store.NewRetryStore(m.Store, cfg.RetryPolicy())
+	for _, environment := range names {
+		log.Debug().Msgf("listing managed secrets in environment: %s", environment)
+		descriptions, err := st.List(context.Background(), map[string]string{"Source": "secret-hoard", "Environment": environment})
+		if err != nil {
+			return diff, fmt.Errorf("error listing secrets in environment %s: %w", environment, err)
+		}
+		for _, description := range descriptions {
+			if !inFile[description.Name] {
+				diff = append(diff, DiffEntry{SecretID: description.Name, Extra: true})
+			}
+		}
+	}
+	return diff, nil
+}
@@ -0,0 +1,82 @@
+package uploader
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	cfg := tools.Config{FilePath: writeManifest(t, "")}
+	if _, err := (ManifestProcessor{Store: st}).Process(cfg, &log); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := ManifestProcessor{Store: st}.Diff(cfg, &log)
+	if err != nil || len(diff) != 5 || diff.Drifted() != 0 || diff.Failed() != 0 {
+		t.Fatalf("Diff() = %+v, %v, want 5 in sync", diff, err)
+	}
+
+	if _, err = st.Put(ctx, "text_file/testenv/my_file_type", `{"contents":"changed","sha256Sum":"x"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Tag(ctx, "jsondoc/testenv/some_json_access_type", map[string]string{"Access": "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.Delete(ctx, "snowflake/testenv/mywarehouse/mytype"); err != nil {
+		t.Fatal(err)
+	}
+	extra := map[string]string{"Source": "secret-hoard", "Environment": "testenv"}
+	if _, err = st.Create(ctx, "text_file/testenv/extra", "{}", extra, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = st.Create(ctx, "text_file/prod/other_environment", "{}", map[string]string{"Source": "secret-hoard", "Environment": "prod"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err = ManifestProcessor{Store: st}.Diff(cfg, &log)
+	if err != nil || len(diff) != 6 || diff.Drifted() != 4 {
+		t.Fatalf("Diff() = %+v, %v, want 4 drifted", diff, err)
+	}
+	bySecret := map[string]DiffEntry{}
+	for _, entry := range diff {
+		bySecret[entry.SecretID] = entry
+	}
+	if entry := bySecret["text_file/testenv/my_file_type"]; strings.Join(entry.Fields, ",") != "~ contents,~ sha256Sum" || len(entry.Tags) != 0 {
+		t.Errorf("value differs entry = %+v", entry)
+	}
+	if entry := bySecret["jsondoc/testenv/some_json_access_type"]; len(entry.Fields) != 0 || entry.Tags["Access"] != "some_json_access_type" {
+		t.Errorf("tags differ entry = %+v", entry)
+	}
+	if entry := bySecret["snowflake/testenv/mywarehouse/mytype"]; !entry.Missing || entry.Row != 3 {
+		t.Errorf("missing entry = %+v", entry)
+	}
+	if entry := bySecret["text_file/testenv/extra"]; !entry.Extra {
+		t.Errorf("extra entry = %+v", entry)
+	}
+
+	var buf bytes.Buffer
+	WriteDiff(&buf, diff)
+	output := buf.String()
+	for _, want := range []string{
+		"  ~ text_file/testenv/my_file_type (value differs)\n      ~ contents = (sensitive)\n",
+		"  ~ jsondoc/testenv/some_json_access_type (tags differ)\n      tag Access = \"some_json_access_type\"\n",
+		"  + snowflake/testenv/mywarehouse/mytype (missing)\n",
+		"  - text_file/testenv/extra (extra)\n",
+		"Diff: 1 missing, 1 extra, 1 value differs, 1 tags differ, 0 replicas differ, 0 kms key differs, 0 scheduled for deletion, 2 in sync, 0 failed.\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("WriteDiff() = %s, want %q", output, want)
+		}
+	}
+	if strings.Contains(output, "changed") {
+		t.Errorf("WriteDiff() contains the secret value: %s", output)
+	}
+}
@@ -0,0 +1,128 @@
+package uploader
+
+import (
+	"fmt"
+
+	"github.com/natemarks/secret-hoard/resources"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/rs/zerolog"
+)
+
+// ManifestProcessor implement CSVProcessor for files with any mix of resource types.
+// Every row is converted with the resource type in its ResourceType column. The header
+// must have the columns of every resource type in the file. Files with a .yaml, .yml or
+// .json extension are read as a list of records instead of CSV
+type ManifestProcessor struct {
+	Store store.SecretStore // secret store shared by all the secrets in the file
+	// Confirm asks whether the secrets -prune would delete should be deleted. Without it
+	// pruning requires -auto-approve
+	Confirm func(secretIDs []string) bool
+}
+
+// checkManifestHeader returns an error for columns that don't belong to any of the resource
+// types in the rows and for missing columns of those resource types
+func checkManifestHeader(header []string, rows []tools.CSVRow) error {
+	var columns []tools.CSVColumn
+	checked := map[string]bool{}
+	for _, row := range rows {
+		name := row.Get("ResourceType")
+		resource, ok := resources.Lookup(name)
+		if !ok || checked[name] {
+			continue
+		}
+		checked[name] = true
+		if err := tools.MissingCSVColumns(header, resource.Columns); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		columns = append(columns, resource.Columns...)
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	return tools.UnknownCSVColumns(header, columns)
+}
+
+// Process handles CSV, YAML and JSON files with any mix of resource types. With cfg.Prune
+// the managed secrets that aren't in the file are scheduled for deletion afterwards
+func (m ManifestProcessor) Process(cfg tools.Config, log *zerolog.Logger) (results Results, err error) {
+	results, secrets, retries, err := m.readSecrets(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	results = ProcessSecrets(cfg, results, secrets, log).countRetries(retries)
+	if cfg.Prune {
+		return m.prune(cfg, results, secrets, log)
+	}
+	return results, nil
+}
+
+// readSecrets converts every record of the file to a secret that uses its own RetryStore.
+// secrets[i] and retries[i] are nil when results[i] has the conversion error
+func (m ManifestProcessor) readSecrets(cfg tools.Config, log *zerolog.Logger) (results Results, secrets []resources.Writer, retries []*store.RetryStore, err error) {
+	if tools.IsManifestFile(cfg.FilePath) {
+		return m.readManifest(cfg, log)
+	}
+	header, rows, err := tools.ReadCSVFile(cfg.FilePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading secrets from file %s: %w", cfg.FilePath, err)
+	}
+	if err = checkManifestHeader(header, rows); err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading secrets from file %s: row 1: %w", cfg.FilePath, err)
+	}
+	results = make(Results, len(rows))
+	secrets = make([]resources.Writer, len(rows))
+	retries = make([]*store.RetryStore, len(rows))
+	for i, row := range rows {
+		results[i].Row = row.Row
+		results[i].ResourceType = row.Get("ResourceType")
+		resource, ok := resources.Lookup(results[i].ResourceType)
+		if !ok {
+			results[i].Err = row.Errorf("unknown ResourceType %q, expected one of %v", results[i].ResourceType, resources.Names())
+			log.Error().Err(results[i].Err).Msg("error converting record to secret")
+			continue
+		}
+		secret, err := resource.FromCSVRow(row.Select(resource.Columns), log)
+		if err != nil {
+			results[i].Err = row.Errorf("%w", err)
+			log.Error().Err(results[i].Err).Msg("error converting record to secret")
+			continue
+		}
+		retries[i] = store.NewRetryStore(m.Store, cfg.RetryPolicy())
+		configure(secret, retries[i], cfg)
+		secrets[i] = secret
+	}
+	return results, secrets, retries, nil
+}
+
+// readManifest converts the records of YAML and JSON manifests. Result rows are the line
+// numbers of the records
+func (m ManifestProcessor) readManifest(cfg tools.Config, log *zerolog.Logger) (results Results, secrets []resources.Writer, retries []*store.RetryStore, err error) {
+	entries, err := tools.ReadManifestFile(cfg.FilePath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error reading secrets from file %s: %w", cfg.FilePath, err)
+	}
+	results = make(Results, len(entries))
+	secrets = make([]resources.Writer, len(entries))
+	retries = make([]*store.RetryStore, len(entries))
+	for i, entry := range entries {
+		results[i].Row = entry.Line
+		results[i].ResourceType = entry.ResourceType
+		resource, ok := resources.Lookup(entry.ResourceType)
+		if !ok {
+			results[i].Err = entry.Errorf("unknown resourceType %q, expected one of %v", entry.ResourceType, resources.Names())
+			log.Error().Err(results[i].Err).Msg("error converting record to secret")
+			continue
+		}
+		secret, err := resource.FromManifest(entry, log)
+		if err != nil {
+			results[i].Err = entry.Errorf("%w", err)
+			log.Error().Err(results[i].Err).Msg("error converting record to secret")
+			continue
+		}
+		retries[i] = store.NewRetryStore(m.Store, cfg.RetryPolicy())
+		configure(secret, retries[i], cfg)
+		secrets[i] = secret
+	}
+	return results, secrets, retries, nil
+}
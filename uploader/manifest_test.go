@@ -0,0 +1,155 @@
+package uploader
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+// writeManifest writes the example manifest to a temp file with the example paths relative
+// to the uploader package and the extra lines appended
+func writeManifest(t *testing.T, header string, lines ...string) string {
+	t.Helper()
+	content, err := os.ReadFile("../examples/manifest_example.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := strings.ReplaceAll(string(content), "examples/", "../examples/")
+	if header != "" {
+		manifest = header + manifest[strings.Index(manifest, "\n"):]
+	}
+	manifest += strings.Join(lines, "")
+	path := t.TempDir() + "/manifest.csv"
+	if err = os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestManifestProcessor(t *testing.T) {
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	cfg := tools.Config{FilePath: writeManifest(t, "")}
+
+	results, err := ManifestProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionCreate) != 5 || results.Failed() != 0 {
+		t.Fatalf("Process() = %v, %v, want 5 created", results, err)
+	}
+	want := []string{"rdspostgres", "snowflake", "ssl_certificate", "jsondoc", "text_file"}
+	for i, result := range results {
+		if result.ResourceType != want[i] || result.Row != i+2 {
+			t.Errorf("results[%d] = %+v, want %s on row %d", i, result, want[i], i+2)
+		}
+	}
+	results, err = ManifestProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionUnchanged) != 5 {
+		t.Fatalf("Process() = %v, %v, want 5 unchanged", results, err)
+	}
+}
+
+func TestManifestProcessorErrors(t *testing.T) {
+	log := tools.TestLogger()
+
+	cfg := tools.Config{FilePath: writeManifest(t, "", "redis,testenv,,,,,,,,,,,,,,,,\n")}
+	results, err := ManifestProcessor{Store: store.NewMemoryStore()}.Process(cfg, &log)
+	if err != nil || results.Failed() != 1 || results.Count(store.ActionCreate) != 5 {
+		t.Fatalf("Process() = %v, %v, want 1 failed", results, err)
+	}
+	if got := results[5].Err; got == nil || !strings.Contains(got.Error(), `row 7: unknown ResourceType "redis"`) {
+		t.Errorf("results[5].Err = %v", got)
+	}
+
+	header := "ResourceType,Environment,Instance,Database,Access,Password,Engine,Port,DbInstanceIdentifier,Host,Username" +
+		",Warehouse,AccountName,CommonName,CertificateFile,PrivateKeyFile,JSONFilePath,Owner"
+	cfg = tools.Config{FilePath: writeManifest(t, header)}
+	if _, err = (ManifestProcessor{Store: store.NewMemoryStore()}).Process(cfg, &log); err == nil ||
+		!strings.Contains(err.Error(), "row 1: text_file:") {
+		t.Errorf("Process() error = %v, want missing text_file column", err)
+	}
+
+	cfg = tools.Config{FilePath: "../examples/snowflake_example.csv"}
+	if results, err = (ManifestProcessor{Store: store.NewMemoryStore()}).Process(cfg, &log); err != nil ||
+		results.Count(store.ActionCreate) != 1 {
+		t.Errorf("Process() = %v, %v, want single type file", results, err)
+	}
+}
+
+// writeFile writes content to a temp file with the given name
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := t.TempDir() + "/" + name
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestManifestProcessorYAML(t *testing.T) {
+	log := tools.TestLogger()
+	content, err := os.ReadFile("../examples/manifest_example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := store.NewMemoryStore()
+	cfg := tools.Config{FilePath: writeFile(t, "manifest.yaml", strings.ReplaceAll(string(content), "examples/", "../examples/"))}
+	results, err := ManifestProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionCreate) != 5 || results.Failed() != 0 {
+		t.Fatalf("Process() = %v, %v, want 5 created", results, err)
+	}
+	if results[1].Row != 12 || results[1].SecretID != "snowflake/testenv/mywarehouse/mytype" {
+		t.Errorf("results[1] = %+v", results[1])
+	}
+
+	// the CSV and JSON manifests describe the same secrets
+	for _, path := range []string{writeManifest(t, ""), "../examples/manifest_example.json"} {
+		results, err = ManifestProcessor{Store: st}.Process(tools.Config{FilePath: path}, &log)
+		if err != nil || results.Failed() != 0 || results.Count(store.ActionUnchanged) != len(results) {
+			t.Errorf("Process(%s) = %v, %v, want all unchanged", path, results, err)
+		}
+	}
+}
+
+func TestManifestProcessorYAMLErrors(t *testing.T) {
+	log := tools.TestLogger()
+	manifest := `- resourceType: snowflake
+  environment: testenv
+  warehouse: mywarehouse
+  access: mytype
+  accountName: myAccountname
+  username: myusername
+  password: |
+    multi
+    line
+- resourceType: redis
+  environment: testenv
+- resourceType: text_file
+  environment: testenv
+  access: my_file_type
+  path: examples/text_file_example.txt
+`
+	cfg := tools.Config{FilePath: writeFile(t, "manifest.yml", manifest)}
+	results, err := ManifestProcessor{Store: store.NewMemoryStore()}.Process(cfg, &log)
+	if err != nil || len(results) != 3 || results.Count(store.ActionCreate) != 1 || results.Failed() != 2 {
+		t.Fatalf("Process() = %v, %v, want 1 created and 2 failed", results, err)
+	}
+	if got := results[1].Err; got == nil || !strings.Contains(got.Error(), `line 10: unknown resourceType "redis"`) {
+		t.Errorf("results[1].Err = %v", got)
+	}
+	if got := results[2].Err; got == nil || !strings.Contains(got.Error(), `line 12: json: unknown field "path"`) {
+		t.Errorf("results[2].Err = %v", got)
+	}
+
+	for name, content := range map[string]string{
+		"map.yaml":     "resourceType: snowflake\n",
+		"missing.yaml": "- environment: testenv\n",
+		"invalid.json": `[{"resourceType": "snowflake",]`,
+	} {
+		cfg = tools.Config{FilePath: writeFile(t, name, content)}
+		if _, err = (ManifestProcessor{Store: store.NewMemoryStore()}).Process(cfg, &log); err == nil {
+			t.Errorf("Process(%s) error = nil", name)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package uploader
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/natemarks/secret-hoard/store"
+)
+
+// planSymbols prefix each secret in the plan like terraform plan does
+var planSymbols = map[store.Action]string{
+	store.ActionCreate:    "+",
+	store.ActionUpdate:    "~",
+	store.ActionUnchanged: "=",
+	store.ActionSkip:      "!",
+	store.ActionRestore:   "^",
+}
+
+// WritePlan writes a terraform style plan of the changes. Secret values are masked: only
+// the names of the changed fields are written
+func WritePlan(w io.Writer, changes []store.Change) {
+	counts := map[store.Action]int{}
+	for _, change := range changes {
+		counts[change.Action]++
+		_, _ = fmt.Fprintf(w, "  %s %s (%s)\n", planSymbols[change.Action], change.SecretID, change.Action)
+		if change.Action == store.ActionUnchanged {
+			continue
+		}
+		for _, field := range change.Fields {
+			_, _ = fmt.Fprintf(w, "      %s = (sensitive)\n", field)
+		}
+		var keys []string
+		for key := range change.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, _ = fmt.Fprintf(w, "      tag %s = %q\n", key, change.Tags[key])
+		}
+		for _, replica := range change.Replicas {
+			_, _ = fmt.Fprintf(w, "      replica %s\n", replica)
+		}
+		if change.KmsKeyID != "" {
+			_, _ = fmt.Fprintf(w, "      kms key = %s\n", change.KmsKeyID)
+		}
+		if change.Action == store.ActionSkip {
+			_, _ = fmt.Fprintln(w, "      overwrite is false, run with -overwrite to update")
+		}
+		if change.Action == store.ActionRestore {
+			_, _ = fmt.Fprintln(w, "      scheduled for deletion, it's restored and then updated from the record")
+		}
+	}
+	_, _ = fmt.Fprintf(w, "\nPlan: %d to create, %d to update, %d unchanged, %d skipped, %d to restore.\n",
+		counts[store.ActionCreate], counts[store.ActionUpdate], counts[store.ActionUnchanged], counts[store.ActionSkip],
+		counts[store.ActionRestore])
+}
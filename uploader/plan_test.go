@@ -0,0 +1,48 @@
+package uploader
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/snowflake"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+func TestWritePlan(t *testing.T) {
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	secret, err := snowflake.FromCSVRecord(snowflake.Record{
+		ResourceType: "snowflake",
+		Environment:  "testenv",
+		Warehouse:    "mywarehouse",
+		Access:       "mytype",
+		AccountName:  "myAccountname",
+		Username:     "myusername",
+		Password:     "mypassword",
+	}, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.Store = st
+	change, err := secret.Plan(false, &log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := secret.Exists(&log); exists || err != nil {
+		t.Fatal("Plan() wrote the secret")
+	}
+
+	var buf bytes.Buffer
+	WritePlan(&buf, []store.Change{change})
+	output := buf.String()
+	if strings.Contains(output, "mypassword") {
+		t.Errorf("plan contains the password:\n%s", output)
+	}
+	for _, want := range []string{"+ snowflake/testenv/mywarehouse/mytype (create)", "+ password = (sensitive)", "Plan: 1 to create"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("plan doesn't contain %q:\n%s", want, output)
+		}
+	}
+}
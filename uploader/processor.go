@@ -1,11 +1,249 @@
 package uploader
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/natemarks/secret-hoard/resources"
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
 	"github.com/rs/zerolog"
 )
 
 // CSVProcessor is an interface defining a method for handling data.
 type CSVProcessor interface {
-	Process(cfg tools.Config, log *zerolog.Logger)
+	// Process creates or updates a secret for every record. The error is only set when the
+	// file can't be read. Failed records are reported in the Results
+	Process(cfg tools.Config, log *zerolog.Logger) (Results, error)
+}
+
+// Result is the outcome of processing one record
+type Result struct {
+	Row          int          // CSV row number or manifest line number
+	SecretID     string       // empty if the record couldn't be converted to a secret
+	ResourceType string       // ResourceType column of the record
+	Action       store.Action // create, update, unchanged, skip or delete. empty when Err is set
+	VersionID    string       // resulting AWSCURRENT version. empty in plan mode
+	Restored     bool         // the secret was scheduled for deletion and restored
+	Retries      int          // number of retried Secrets Manager calls
+	Err          error
+}
+
+// Results are the outcomes of processing a file
+type Results []Result
+
+// Count returns the number of results with the action
+func (r Results) Count(action store.Action) (count int) {
+	for _, result := range r {
+		if result.Err == nil && result.Action == action {
+			count++
+		}
+	}
+	return count
+}
+
+// Failed returns the number of failed records
+func (r Results) Failed() (count int) {
+	for _, result := range r {
+		if result.Err != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Restored returns the number of restored secrets
+func (r Results) Restored() (count int) {
+	for _, result := range r {
+		if result.Restored {
+			count++
+		}
+	}
+	return count
+}
+
+// Retries returns the number of retried calls of all the records
+func (r Results) Retries() (count int) {
+	for _, result := range r {
+		count += result.Retries
+	}
+	return count
+}
+
+// Summary returns the number of records by outcome
+func (r Results) Summary() string {
+	return fmt.Sprintf("created: %d, updated: %d, unchanged: %d, skipped: %d, restored: %d, deleted: %d, failed: %d, retries: %d",
+		r.Count(store.ActionCreate), r.Count(store.ActionUpdate), r.Count(store.ActionUnchanged),
+		r.Count(store.ActionSkip), r.Restored(), r.Count(store.ActionDelete), r.Failed(), r.Retries())
+}
+
+// countRetries sets the Retries of every result from the RetryStore of its secret.
+// stores[i] is nil when the record couldn't be converted
+func (r Results) countRetries(stores []*store.RetryStore) Results {
+	for i, st := range stores {
+		if st != nil {
+			r[i].Retries = st.Retries()
+		}
+	}
+	return r
+}
+
+// replicaRegions returns the replica regions of a record or cfg.ReplicaRegions when it has none
+func replicaRegions(cfg tools.Config, regions []string) []string {
+	if len(regions) == 0 {
+		return cfg.ReplicaRegions
+	}
+	return regions
+}
+
+// kmsKeyID returns the KMS key of a record or, when it has none, the cfg.KmsKeys key of its
+// environment or cfg.KmsKeyID
+func kmsKeyID(cfg tools.Config, environment, keyID string) string {
+	if keyID != "" {
+		return keyID
+	}
+	if keyID, ok := cfg.KmsKeys[environment]; ok {
+		return keyID
+	}
+	return cfg.KmsKeyID
+}
+
+// configure sets the store of a secret and the cfg defaults of the options its record
+// doesn't set
+func configure(secret resources.Secret, st store.SecretStore, cfg tools.Config) {
+	options := secret.SecretOptions()
+	options.Store = st
+	options.ReplicaRegions = replicaRegions(cfg, options.ReplicaRegions)
+	options.KmsKeyID = kmsKeyID(cfg, secret.Tags()["Environment"], options.KmsKeyID)
+}
+
+// ProcessSecrets creates or updates secrets[i] for every result without an error.
+// results[i] is the result of the record that secrets[i] was converted from. With cfg.Plan
+// it only prints the plan. Up to cfg.Concurrency records are processed at the same time and
+// the results keep the order of the records
+func ProcessSecrets[S resources.Writer](cfg tools.Config, results Results, secrets []S, log *zerolog.Logger) Results {
+	if cfg.Plan {
+		changes := make([]*store.Change, len(secrets))
+		forEach(cfg.Concurrency, len(secrets), func(i int) {
+			if results[i].Err != nil {
+				return
+			}
+			secret := secrets[i]
+			results[i].SecretID = secret.SecretID()
+			change, err := secret.Plan(cfg.Overwrite, log)
+			if errors.Is(err, store.ErrScheduledForDeletion) && cfg.RestoreDeleted {
+				// the value can't be compared until the secret is restored
+				change, err = store.Change{SecretID: secret.SecretID(), Action: store.ActionRestore}, nil
+			}
+			if err != nil {
+				log.Error().Err(err).Msgf("error planning secret: %s", secret.SecretID())
+				results[i].Err = err
+				return
+			}
+			changes[i] = &change
+			results[i].Action = change.Action
+		})
+		var planned []store.Change
+		for _, change := range changes {
+			if change != nil {
+				planned = append(planned, *change)
+			}
+		}
+		WritePlan(os.Stdout, planned)
+		return results
+	}
+
+	// records with the same secret ID are written one at a time in the order of the file
+	done := make([]chan struct{}, len(secrets))
+	previous := make([]chan struct{}, len(secrets))
+	last := map[string]chan struct{}{}
+	for i, secret := range secrets {
+		done[i] = make(chan struct{})
+		if results[i].Err == nil {
+			previous[i] = last[secret.SecretID()]
+			last[secret.SecretID()] = done[i]
+		}
+	}
+	forEach(cfg.Concurrency, len(secrets), func(i int) {
+		defer close(done[i])
+		if results[i].Err != nil {
+			return
+		}
+		if previous[i] != nil {
+			<-previous[i]
+		}
+		secret := secrets[i]
+		result := &results[i]
+		result.SecretID = secret.SecretID()
+		exists, err := secret.Exists(log)
+		if errors.Is(err, store.ErrScheduledForDeletion) && cfg.RestoreDeleted {
+			if err = secret.Restore(log); err == nil {
+				result.Restored = true
+			}
+		}
+		switch {
+		case errors.Is(err, store.ErrScheduledForDeletion):
+			result.Err = fmt.Errorf("%w, run with -restore-deleted to restore it", err)
+		case err != nil:
+			result.Err = err
+		case exists:
+			result.Action, result.VersionID, result.Err = secret.Update(cfg.Overwrite, log)
+		default:
+			result.Action = store.ActionCreate
+			result.VersionID, result.Err = secret.Create(log)
+		}
+		if result.Err != nil {
+			result.Action = ""
+		}
+	})
+	return results
+}
+
+// forEach calls fn with 0 to n-1 from a pool of concurrency workers
+func forEach(concurrency, n int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < concurrency && worker < n; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// Run processes the file, logs the summary and writes the report if cfg.ReportPath is set. It returns an error if the file can't be
+// read or any record failed so the commands can exit non-zero
+func Run(processor CSVProcessor, cfg tools.Config, log *zerolog.Logger) error {
+	results, err := processor.Process(cfg, log)
+	if cfg.ReportPath != "" {
+		if reportErr := NewReport(cfg, results, err).Write(cfg.ReportPath); reportErr != nil {
+			log.Error().Err(reportErr).Msgf("error writing report: %s", cfg.ReportPath)
+			if err == nil {
+				err = reportErr
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !cfg.Plan {
+		log.Info().Msgf("summary: %s", results.Summary())
+	}
+	if failed := results.Failed(); failed > 0 {
+		return fmt.Errorf("%d of %d records failed", failed, len(results))
+	}
+	return nil
 }
@@ -0,0 +1,340 @@
+package uploader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+// failingStore fails every Create
+type failingStore struct {
+	*store.MemoryStore
+}
+
+func (f failingStore) Create(_ context.Context, _, _ string, _ map[string]string, _ string) (string, error) {
+	return "", errors.New("AccessDeniedException")
+}
+
+func TestRun(t *testing.T) {
+	log := tools.TestLogger()
+	cfg := tools.Config{FilePath: "../examples/snowflake_example.csv"}
+	st := store.NewMemoryStore()
+
+	results, err := SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionCreate) != 1 {
+		t.Fatalf("Process() = %v, %v, want 1 created", results, err)
+	}
+	results, err = SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionUnchanged) != 1 {
+		t.Fatalf("Process() = %v, %v, want 1 unchanged", results, err)
+	}
+	if err = Run(SnowflakeProcessor{Store: failingStore{store.NewMemoryStore()}}, cfg, &log); err == nil {
+		t.Fatal("Run() error = nil, want failed records")
+	}
+	if err = Run(SnowflakeProcessor{Store: st}, tools.Config{FilePath: "missing.csv"}, &log); err == nil {
+		t.Fatal("Run() error = nil, want missing file")
+	}
+}
+
+func TestRunReport(t *testing.T) {
+	log := tools.TestLogger()
+	reportPath := t.TempDir() + "/report.json"
+	cfg := tools.Config{FilePath: "../examples/snowflake_example.csv", ReportPath: reportPath}
+	if err := Run(SnowflakeProcessor{Store: store.NewMemoryStore()}, cfg, &log); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err = json.Unmarshal(content, &report); err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Records) != 1 || report.Summary["create"] != 1 {
+		t.Fatalf("report = %+v", report)
+	}
+	record := report.Records[0]
+	if record.SecretID != "snowflake/myenvironment/mywarehouse/mytype" || record.ResourceType != "snowflake" ||
+		record.Action != store.ActionCreate || record.VersionID == "" || record.Error != "" {
+		t.Errorf("report record = %+v", record)
+	}
+}
+
+func TestProcessConcurrency(t *testing.T) {
+	log := tools.TestLogger()
+	lines := []string{"ResourceType,Environment,Warehouse,Access,AccountName,Username,Password\n"}
+	for i := 0; i < 50; i++ {
+		lines = append(lines, fmt.Sprintf("snowflake,testenv,warehouse%d,mytype,myAccountname,myusername,mypassword\n", i))
+	}
+	// the same secret twice is written in the order of the file
+	lines = append(lines, "snowflake,testenv,warehouse0,mytype,myAccountname,myusername,newpassword\n")
+	cfg := tools.Config{FilePath: writeFile(t, "snowflake.csv", strings.Join(lines, "")), Overwrite: true, Concurrency: 8}
+	st := store.NewMemoryStore()
+
+	cfg.Plan = true
+	results, err := SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionCreate) != 51 {
+		t.Fatalf("Process() = %v, %v, want 51 planned creates", results, err)
+	}
+	cfg.Plan = false
+	results, err = SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionCreate) != 50 || results[50].Action != store.ActionUpdate {
+		t.Fatalf("Process() = %v, %v, want 50 created and the last row updated", results, err)
+	}
+	for i, result := range results {
+		if want := fmt.Sprintf("snowflake/testenv/warehouse%d/mytype", i%50); result.Row != i+2 || result.SecretID != want {
+			t.Errorf("results[%d] = %+v, want row %d %s", i, result, i+2, want)
+		}
+	}
+}
+
+// throttledStore throttles the first Create of every secret
+type throttledStore struct {
+	*store.MemoryStore
+	throttled sync.Map
+}
+
+func (s *throttledStore) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (string, error) {
+	if _, throttled := s.throttled.LoadOrStore(secretID, true); !throttled {
+		return "", &smithy.GenericAPIError{Code: "ThrottlingException"}
+	}
+	return s.MemoryStore.Create(ctx, secretID, value, tags, kmsKeyID)
+}
+
+func TestRunRetries(t *testing.T) {
+	log := tools.TestLogger()
+	reportPath := t.TempDir() + "/report.json"
+	cfg := tools.Config{FilePath: "../examples/snowflake_example.csv", ReportPath: reportPath, MaxAttempts: 2, RetryDelay: time.Millisecond}
+	if err := Run(SnowflakeProcessor{Store: &throttledStore{MemoryStore: store.NewMemoryStore()}}, cfg, &log); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	content, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report Report
+	if err = json.Unmarshal(content, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Summary["retries"] != 1 || report.Records[0].Retries != 1 || report.Records[0].Action != store.ActionCreate {
+		t.Errorf("report = %+v", report)
+	}
+
+	cfg.MaxAttempts = 1
+	if err = Run(SnowflakeProcessor{Store: &throttledStore{MemoryStore: store.NewMemoryStore()}}, cfg, &log); err == nil {
+		t.Error("Run() error = nil, want throttled record without retries")
+	}
+}
+
+// describeStore fails every Describe with err or marks the secrets scheduled for deletion.
+// It counts the writes
+type describeStore struct {
+	*store.MemoryStore
+	err         error
+	deletedDate *time.Time
+	writes      int
+}
+
+func (s *describeStore) Describe(ctx context.Context, secretID string) (store.Description, error) {
+	if s.err != nil {
+		return store.Description{}, s.err
+	}
+	description, err := s.MemoryStore.Describe(ctx, secretID)
+	description.DeletedDate = s.deletedDate
+	return description, err
+}
+
+func (s *describeStore) Create(ctx context.Context, secretID, value string, tags map[string]string, kmsKeyID string) (string, error) {
+	s.writes++
+	return s.MemoryStore.Create(ctx, secretID, value, tags, kmsKeyID)
+}
+
+func (s *describeStore) Put(ctx context.Context, secretID, value string) (string, error) {
+	s.writes++
+	return s.MemoryStore.Put(ctx, secretID, value)
+}
+
+func (s *describeStore) Restore(_ context.Context, _ string) error {
+	s.deletedDate = nil
+	return nil
+}
+
+func TestRunDescribeErrors(t *testing.T) {
+	log := tools.TestLogger()
+	cfg := tools.Config{FilePath: "../examples/snowflake_example.csv", Overwrite: true}
+
+	accessDenied := errors.New("AccessDeniedException")
+	st := &describeStore{MemoryStore: store.NewMemoryStore(), err: accessDenied}
+	results, err := SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Failed() != 1 || !errors.Is(results[0].Err, accessDenied) || st.writes != 0 {
+		t.Errorf("Process() = %+v, %v, writes = %d, want AccessDeniedException without writes", results, err, st.writes)
+	}
+
+	deletedDate := time.Now().Add(24 * time.Hour)
+	st = &describeStore{MemoryStore: store.NewMemoryStore(), deletedDate: &deletedDate}
+	if _, err = st.MemoryStore.Create(context.Background(), "snowflake/myenvironment/mywarehouse/mytype", "{}", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	results, err = SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Failed() != 1 || !errors.Is(results[0].Err, store.ErrScheduledForDeletion) || st.writes != 0 {
+		t.Errorf("Process() = %+v, %v, writes = %d, want ErrScheduledForDeletion without writes", results, err, st.writes)
+	}
+}
+
+func TestRunRestoreDeleted(t *testing.T) {
+	log := tools.TestLogger()
+	cfg := tools.Config{FilePath: "../examples/snowflake_example.csv", Overwrite: true, RestoreDeleted: true}
+	deletedDate := time.Now().Add(24 * time.Hour)
+	st := &describeStore{MemoryStore: store.NewMemoryStore(), deletedDate: &deletedDate}
+	if _, err := st.MemoryStore.Create(context.Background(), "snowflake/myenvironment/mywarehouse/mytype", "{}", nil, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.Plan = true
+	results, err := SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionRestore) != 1 || st.deletedDate == nil {
+		t.Fatalf("Process() = %+v, %v, want a planned restore", results, err)
+	}
+	cfg.Plan = false
+	results, err = SnowflakeProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Failed() != 0 || !results[0].Restored || results[0].Action != store.ActionUpdate || st.deletedDate != nil {
+		t.Fatalf("Process() = %+v, %v, want restored and updated", results, err)
+	}
+}
+
+func TestProcessReplicaRegions(t *testing.T) {
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	path := t.TempDir() + "/textfile.csv"
+	content := "ResourceType,Environment,Access,FilePath,ReplicaRegions\n" +
+		"text_file,testenv,one,../examples/text_file_example.txt,us-west-2;eu-west-1\n" +
+		"text_file,testenv,two,../examples/text_file_example.txt,\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	replicas := func(secretID string) string {
+		t.Helper()
+		description, err := st.Describe(context.Background(), secretID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(description.ReplicaRegions(), ",")
+	}
+
+	// the row regions replace the -replica-regions default
+	cfg := tools.Config{FilePath: path, ReplicaRegions: []string{"ap-south-1"}}
+	results, err := ManifestProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionCreate) != 2 {
+		t.Fatalf("Process() = %+v, %v, want 2 created", results, err)
+	}
+	if got := replicas("text_file/testenv/one"); got != "eu-west-1,us-west-2" {
+		t.Errorf("replicas of one = %s", got)
+	}
+	if got := replicas("text_file/testenv/two"); got != "ap-south-1" {
+		t.Errorf("replicas of two = %s", got)
+	}
+
+	// missing and extra replicas are reconciled on update
+	cfg = tools.Config{FilePath: path, ReplicaRegions: []string{"us-west-2"}, Overwrite: true}
+	results, err = TextFileProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionUpdate) != 1 || results.Count(store.ActionUnchanged) != 1 {
+		t.Fatalf("Process() = %+v, %v, want 1 updated and 1 unchanged", results, err)
+	}
+	if got := replicas("text_file/testenv/two"); got != "us-west-2" {
+		t.Errorf("replicas of two = %s", got)
+	}
+	diff, err := ManifestProcessor{Store: st}.Diff(tools.Config{FilePath: path}, &log)
+	if err != nil || diff.Drifted() != 0 {
+		t.Errorf("Diff() = %+v, %v, want in sync", diff, err)
+	}
+}
+
+func TestProcessKMSKeys(t *testing.T) {
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	key := func(name string) string {
+		return "arn:aws:kms:us-east-1:111122223333:key/" + name
+	}
+	dir := t.TempDir()
+	path := dir + "/textfile.csv"
+	content := "ResourceType,Environment,Access,FilePath,KmsKeyId\n" +
+		"text_file,testenv,one,../examples/text_file_example.txt," + key("one") + "\n" +
+		"text_file,testenv,two,../examples/text_file_example.txt,\n" +
+		"text_file,prodenv,two,../examples/text_file_example.txt,\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keysPath := dir + "/kms_keys.yaml"
+	if err := os.WriteFile(keysPath, []byte("prodenv: "+key("prodenv")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	kmsKeys, err := tools.ReadKMSKeys(keysPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kmsKey := func(secretID string) string {
+		t.Helper()
+		description, err := st.Describe(context.Background(), secretID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return description.KmsKeyID
+	}
+
+	// the row key replaces the environment key, which replaces the -kms-key-id default
+	cfg := tools.Config{FilePath: path, KmsKeyID: key("default"), KmsKeys: kmsKeys}
+	results, err := ManifestProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionCreate) != 3 {
+		t.Fatalf("Process() = %+v, %v, want 3 created", results, err)
+	}
+	for secretID, want := range map[string]string{
+		"text_file/testenv/one": key("one"),
+		"text_file/testenv/two": key("default"),
+		"text_file/prodenv/two": key("prodenv"),
+	} {
+		if got := kmsKey(secretID); got != want {
+			t.Errorf("KMS key of %s = %s, want %s", secretID, got, want)
+		}
+	}
+
+	// a new key is drift and is only set with -overwrite
+	cfg.KmsKeyID = key("new")
+	diff, err := ManifestProcessor{Store: st}.Diff(cfg, &log)
+	if err != nil || diff.Drifted() != 1 {
+		t.Fatalf("Diff() = %+v, %v, want 1 drifted", diff, err)
+	}
+	cfg.Overwrite = true
+	results, err = TextFileProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionUpdate) != 1 || results.Count(store.ActionUnchanged) != 2 {
+		t.Fatalf("Process() = %+v, %v, want 1 updated and 2 unchanged", results, err)
+	}
+	if got := kmsKey("text_file/testenv/two"); got != key("new") {
+		t.Errorf("KMS key of two = %s, want %s", got, key("new"))
+	}
+
+	// the key isn't managed without a row, environment or default key
+	if diff, err = (ManifestProcessor{Store: st}).Diff(tools.Config{FilePath: path}, &log); err != nil || diff.Drifted() != 0 {
+		t.Errorf("Diff() = %+v, %v, want in sync", diff, err)
+	}
+
+	// aliases and key IDs would never match the key ARN Secrets Manager returns
+	content = "ResourceType,Environment,Access,FilePath,KmsKeyId\n" +
+		"text_file,testenv,three,../examples/text_file_example.txt,alias/three\n"
+	if err = os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if results, err = (ManifestProcessor{Store: st}).Process(tools.Config{FilePath: path}, &log); err != nil || results.Failed() != 1 {
+		t.Errorf("Process() = %+v, %v, want 1 failed", results, err)
+	}
+}
@@ -0,0 +1,114 @@
+package uploader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/natemarks/secret-hoard/resources"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/rs/zerolog"
+)
+
+// pruneScope is a ResourceType and Environment of the records in the file
+type pruneScope struct {
+	resourceType string
+	environment  string
+}
+
+// recoveryWindowDays returns the recovery window of pruned secrets
+func recoveryWindowDays(cfg tools.Config) int {
+	if cfg.RecoveryWindowDays == 0 {
+		return store.DefaultRecoveryWindowDays
+	}
+	return cfg.RecoveryWindowDays
+}
+
+// pruneCandidates returns the secrets tagged Source=secret-hoard with the ResourceType and
+// Environment of a record in the file that aren't in the file, sorted by name
+func (m ManifestProcessor) pruneCandidates(cfg tools.Config, secrets []resources.Writer, log *zerolog.Logger) ([]store.Description, error) {
+	inFile := map[string]bool{}
+	scopes := map[pruneScope]bool{}
+	for _, secret := range secrets {
+		if secret != nil {
+			tags := secret.Tags()
+			inFile[secret.SecretID()] = true
+			scopes[pruneScope{tags["ResourceType"], tags["Environment"]}] = true
+		}
+	}
+	st := store.NewRetryStore(m.Store, cfg.RetryPolicy())
+	var candidates []store.Description
+	for scope := range scopes {
+		log.Debug().Msgf("listing managed %s secrets in environment: %s", scope.resourceType, scope.environment)
+		descriptions, err := st.List(context.Background(), map[string]string{
+			"Source":       "secret-hoard",
+			"ResourceType": scope.resourceType,
+			"Environment":  scope.environment,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error listing %s secrets in environment %s: %w", scope.resourceType, scope.environment, err)
+		}
+		for _, description := range descriptions {
+			if !inFile[description.Name] {
+				candidates = append(candidates, description)
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Name < candidates[j].Name })
+	return candidates, nil
+}
+
+// WritePrunePlan writes the secrets that -prune schedules for deletion
+func WritePrunePlan(w io.Writer, candidates []store.Description, recoveryWindowDays int) {
+	for _, candidate := range candidates {
+		_, _ = fmt.Fprintf(w, "  - %s (%s)\n", candidate.Name, store.ActionDelete)
+	}
+	_, _ = fmt.Fprintf(w, "\nPrune: %d to delete after a %d day recovery window.\n", len(candidates), recoveryWindowDays)
+}
+
+// prune schedules the deletion of the managed secrets that aren't in the file and adds their
+// results. Nothing is deleted when a record failed because its secret would look absent. With
+// cfg.Plan it only prints the secrets. Otherwise the deletion must be confirmed by
+// cfg.AutoApprove or m.Confirm
+func (m ManifestProcessor) prune(cfg tools.Config, results Results, secrets []resources.Writer, log *zerolog.Logger) (Results, error) {
+	if failed := results.Failed(); failed > 0 {
+		log.Error().Msgf("not pruning: %d of %d records failed", failed, len(results))
+		return results, nil
+	}
+	candidates, err := m.pruneCandidates(cfg, secrets, log)
+	if err != nil {
+		return results, err
+	}
+	days := recoveryWindowDays(cfg)
+	WritePrunePlan(os.Stdout, candidates, days)
+	pruned := make(Results, len(candidates))
+	for i, candidate := range candidates {
+		pruned[i] = Result{SecretID: candidate.Name, ResourceType: candidate.Tags["ResourceType"], Action: store.ActionDelete}
+	}
+	if cfg.Plan || len(candidates) == 0 {
+		return append(results, pruned...), nil
+	}
+	secretIDs := make([]string, len(candidates))
+	for i, candidate := range candidates {
+		secretIDs[i] = candidate.Name
+	}
+	if !cfg.AutoApprove && (m.Confirm == nil || !m.Confirm(secretIDs)) {
+		return results, fmt.Errorf("deleting %d secrets wasn't confirmed, run with -auto-approve to prune without confirmation", len(candidates))
+	}
+	forEach(cfg.Concurrency, len(pruned), func(i int) {
+		result := &pruned[i]
+		st := store.NewRetryStore(m.Store, cfg.RetryPolicy())
+		deletionDate, err := st.ScheduleDeletion(context.Background(), result.SecretID, days)
+		result.Retries = st.Retries()
+		if err != nil {
+			log.Error().Err(err).Msgf("error scheduling deletion: %s", result.SecretID)
+			result.Action, result.Err = "", err
+			return
+		}
+		log.Info().Msgf("scheduled deletion on %s: %s", deletionDate.Format("2006-01-02"), result.SecretID)
+	})
+	return append(results, pruned...), nil
+}
@@ -0,0 +1,98 @@
+package uploader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+// scheduled returns true if the secret is scheduled for deletion
+func scheduled(t *testing.T, st store.SecretStore, secretID string) bool {
+	t.Helper()
+	description, err := st.Describe(context.Background(), secretID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return description.ScheduledForDeletion()
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	cfg := tools.Config{FilePath: writeManifest(t, "")}
+	if _, err := (ManifestProcessor{Store: st}).Process(cfg, &log); err != nil {
+		t.Fatal(err)
+	}
+	stale := "text_file/testenv/stale"
+	for secretID, tags := range map[string]map[string]string{
+		stale:                      {"Source": "secret-hoard", "ResourceType": "text_file", "Environment": "testenv"},
+		"text_file/prod/stale":     {"Source": "secret-hoard", "ResourceType": "text_file", "Environment": "prod"},
+		"redis/testenv/stale":      {"Source": "secret-hoard", "ResourceType": "redis", "Environment": "testenv"},
+		"text_file/testenv/manual": {"ResourceType": "text_file", "Environment": "testenv"},
+	} {
+		if _, err := st.Create(ctx, secretID, "{}", tags, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg = tools.Config{FilePath: cfg.FilePath, Prune: true, Plan: true}
+	results, err := ManifestProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionDelete) != 1 || results[len(results)-1].SecretID != stale {
+		t.Fatalf("Process(plan) = %+v, %v, want %s deleted", results, err, stale)
+	}
+	if scheduled(t, st, stale) {
+		t.Fatal("Process(plan) deleted a secret")
+	}
+
+	cfg = tools.Config{FilePath: cfg.FilePath, Prune: true, RecoveryWindowDays: 7}
+	for _, confirm := range []func([]string) bool{nil, func([]string) bool { return false }} {
+		if _, err = (ManifestProcessor{Store: st, Confirm: confirm}).Process(cfg, &log); err == nil {
+			t.Error("Process() error = nil without confirmation")
+		}
+		if scheduled(t, st, stale) {
+			t.Fatal("Process() deleted a secret without confirmation")
+		}
+	}
+
+	var confirmed []string
+	confirm := func(secretIDs []string) bool {
+		confirmed = secretIDs
+		return true
+	}
+	results, err = ManifestProcessor{Store: st, Confirm: confirm}.Process(cfg, &log)
+	if err != nil || results.Count(store.ActionDelete) != 1 || results.Failed() != 0 || len(confirmed) != 1 {
+		t.Fatalf("Process() = %+v, %v, want %s deleted", results, err, stale)
+	}
+	description, err := st.Describe(ctx, stale)
+	if err != nil || description.DeletedDate == nil || time.Until(*description.DeletedDate) > 7*24*time.Hour {
+		t.Errorf("Describe() = %+v, %v, want deleted in 7 days", description, err)
+	}
+	for _, secretID := range []string{"text_file/prod/stale", "redis/testenv/stale", "text_file/testenv/manual", "text_file/testenv/my_file_type"} {
+		if scheduled(t, st, secretID) {
+			t.Errorf("Process() deleted %s", secretID)
+		}
+	}
+}
+
+func TestPruneFailedRecords(t *testing.T) {
+	ctx := context.Background()
+	log := tools.TestLogger()
+	st := store.NewMemoryStore()
+	stale := "text_file/testenv/stale"
+	tags := map[string]string{"Source": "secret-hoard", "ResourceType": "text_file", "Environment": "testenv"}
+	if _, err := st.Create(ctx, stale, "{}", tags, ""); err != nil {
+		t.Fatal(err)
+	}
+	cfg := tools.Config{FilePath: writeManifest(t, "", "redis,testenv,,,,,,,,,,,,,,,,\n"), Prune: true, AutoApprove: true}
+	results, err := ManifestProcessor{Store: st}.Process(cfg, &log)
+	if err != nil || results.Failed() != 1 || results.Count(store.ActionDelete) != 0 {
+		t.Fatalf("Process() = %+v, %v, want nothing deleted", results, err)
+	}
+	if scheduled(t, st, stale) {
+		t.Error("Process() pruned with a failed record")
+	}
+}
@@ -1,41 +1,40 @@
 package uploader
 
 import (
-	"strings"
+	"fmt"
 
 	"github.com/natemarks/secret-hoard/rdspostgres"
+	"github.com/natemarks/secret-hoard/store"
 	"github.com/natemarks/secret-hoard/tools"
 	"github.com/rs/zerolog"
 )
 
 // RDSPostgresProcessor implement CSVProcessor for rdspostgres secrets
-type RDSPostgresProcessor struct{}
+type RDSPostgresProcessor struct {
+	Store store.SecretStore // secret store shared by all the secrets in the file
+}
 
 // Process handles the rdspostgres secrets CSV files
-func (r RDSPostgresProcessor) Process(cfg tools.Config, log *zerolog.Logger) {
-	var secrets []rdspostgres.Secret
+func (r RDSPostgresProcessor) Process(cfg tools.Config, log *zerolog.Logger) (results Results, err error) {
 	records, err := rdspostgres.RecordsFromCSV(cfg.FilePath, log)
 	if err != nil {
-		log.Fatal().Err(err).Msgf("error reading secrets from file %s", cfg.FilePath)
+		return nil, fmt.Errorf("error reading secrets from file %s: %w", cfg.FilePath, err)
 	}
-	for _, record := range records {
-		// skip header row
-		if strings.ToLower(record.ResourceType) == "resourcetype" {
-			continue
-		}
+	results = make(Results, len(records))
+	secrets := make([]rdspostgres.Secret, len(records))
+	retries := make([]*store.RetryStore, len(records))
+	for i, record := range records {
+		results[i].Row = record.Row
+		results[i].ResourceType = record.ResourceType
 		secret, err := rdspostgres.FromCSVRecord(record, log)
 		if err != nil {
-			log.Error().Err(err).Msgf("error converting record to secret: %v", record)
-			continue
-		}
-		secrets = append(secrets, secret)
-	}
-
-	for _, secret := range secrets {
-		if secret.Exists(log) {
-			secret.Update(cfg.Overwrite, log)
+			log.Error().Err(err).Object("record", record).Msgf("row %d: error converting record to secret", record.Row)
+			results[i].Err = fmt.Errorf("row %d: %w", record.Row, err)
 			continue
 		}
-		secret.Create(log)
+		retries[i] = store.NewRetryStore(r.Store, cfg.RetryPolicy())
+		configure(&secret, retries[i], cfg)
+		secrets[i] = secret
 	}
+	return ProcessSecrets(cfg, results, secrets, log).countRetries(retries), nil
 }
@@ -0,0 +1,217 @@
+package uploader
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/natemarks/secret-hoard/jsondoc"
+	"github.com/natemarks/secret-hoard/rdspostgres"
+	"github.com/natemarks/secret-hoard/snowflake"
+	"github.com/natemarks/secret-hoard/sslcert"
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/textfile"
+	"github.com/natemarks/secret-hoard/tools"
+	"github.com/rs/zerolog"
+)
+
+// chdirRoot runs the test from the repository root so the example file paths resolve
+func chdirRoot(t *testing.T) {
+	t.Helper()
+	dir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(dir) })
+}
+
+// exampleSecrets returns the passwords of the example CSVs, the leaf values of their JSON
+// documents and the lines of their other secret files
+func exampleSecrets(t *testing.T) (secrets []string) {
+	t.Helper()
+	csvFiles, err := filepath.Glob("examples/*.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, csvFile := range csvFiles {
+		_, rows, err := tools.ReadCSVFile(csvFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, row := range rows {
+			if row.Get("Password") != "" {
+				secrets = append(secrets, row.Get("Password"))
+			}
+			for _, column := range []string{"PrivateKeyFile", "JSONFilePath", "File", "FilePath"} {
+				if row.Get(column) == "" {
+					continue
+				}
+				content, err := os.ReadFile(row.Get(column))
+				if err != nil {
+					t.Fatal(err)
+				}
+				var document any
+				if json.Unmarshal(content, &document) == nil {
+					secrets = append(secrets, jsonLeaves(document)...)
+					continue
+				}
+				for _, line := range strings.Split(string(content), "\n") {
+					// the PEM markers aren't secret
+					if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "-----") {
+						secrets = append(secrets, line)
+					}
+				}
+			}
+		}
+	}
+	if len(secrets) == 0 {
+		t.Fatal("no secrets in the example CSV files")
+	}
+	return secrets
+}
+
+// jsonLeaves returns the string and number values of a JSON document
+func jsonLeaves(document any) (leaves []string) {
+	switch value := document.(type) {
+	case map[string]any:
+		for _, item := range value {
+			leaves = append(leaves, jsonLeaves(item)...)
+		}
+	case []any:
+		for _, item := range value {
+			leaves = append(leaves, jsonLeaves(item)...)
+		}
+	case string:
+		leaves = append(leaves, value)
+	case float64:
+		leaves = append(leaves, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	return leaves
+}
+
+// logStrings returns the string values of every JSON log line. Field names are left out so a
+// secret that is also a field name ex. password isn't reported
+func logStrings(t *testing.T, output string) (values []string) {
+	t.Helper()
+	var walk func(value any)
+	walk = func(value any) {
+		switch value := value.(type) {
+		case map[string]any:
+			for _, item := range value {
+				walk(item)
+			}
+		case []any:
+			for _, item := range value {
+				walk(item)
+			}
+		case string:
+			values = append(values, value)
+		}
+	}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		walk(entry)
+	}
+	return values
+}
+
+// containsSecret returns true if value contains secret delimited by characters that can't be
+// part of a word or a base64 string, so the short secret "AAA" isn't found in a certificate
+func containsSecret(value, secret string) bool {
+	pattern := regexp.MustCompile(`(^|[^A-Za-z0-9+/=_])` + regexp.QuoteMeta(secret) + `($|[^A-Za-z0-9+/=_])`)
+	return pattern.MatchString(value)
+}
+
+// logValues logs values with every format the code uses
+func logValues(log *zerolog.Logger, values ...any) {
+	for _, value := range values {
+		event := log.Debug()
+		if marshaler, ok := value.(zerolog.LogObjectMarshaler); ok {
+			event = event.Object("value", marshaler)
+		}
+		event.Msgf("%v %+v %s", value, value, value)
+	}
+}
+
+func TestLogsRedactSecrets(t *testing.T) {
+	chdirRoot(t)
+	secrets := exampleSecrets(t)
+	var buf bytes.Buffer
+	log := zerolog.New(&buf).Level(zerolog.DebugLevel)
+
+	// processing, planning and reprocessing every example
+	examples, err := filepath.Glob("examples/*_example.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	examples = append(examples, "examples/manifest_example.yaml", "examples/manifest_example.json")
+	st := store.NewMemoryStore()
+	for _, path := range examples {
+		for _, cfg := range []tools.Config{
+			{FilePath: path, Plan: true},
+			{FilePath: path},
+			{FilePath: path, Overwrite: true},
+		} {
+			log.Info().Object("config", cfg).Msg("config")
+			if _, err = (ManifestProcessor{Store: st}).Process(cfg, &log); err != nil {
+				t.Fatalf("Process(%s) error = %v", path, err)
+			}
+		}
+	}
+	cfg := tools.Config{FilePath: "examples/rdspostgres_example.csv", Overwrite: true}
+	_ = Run(RDSPostgresProcessor{Store: failingStore{store.NewMemoryStore()}}, cfg, &log)
+	cfg = tools.Config{FilePath: "examples/snowflake_example.csv", Overwrite: true}
+	_ = Run(SnowflakeProcessor{Store: failingStore{store.NewMemoryStore()}}, cfg, &log)
+
+	// records and secret data
+	rdsRecords, _ := rdspostgres.RecordsFromCSV("examples/rdspostgres_example.csv", &log)
+	for _, record := range rdsRecords {
+		secret, _ := rdspostgres.FromCSVRecord(record, &log)
+		logValues(&log, record, secret.Data, secret)
+	}
+	snowflakeRecords, _ := snowflake.RecordsFromCSV("examples/snowflake_example.csv", &log)
+	for _, record := range snowflakeRecords {
+		secret, _ := snowflake.FromCSVRecord(record, &log)
+		logValues(&log, record, secret.Data, secret)
+	}
+	sslcertRecords, _ := sslcert.RecordsFromCSV("examples/sslcert_example.csv", &log)
+	for _, record := range sslcertRecords {
+		secret, _ := sslcert.FromCSVRecord(record, &log)
+		logValues(&log, record, secret.Data, secret)
+	}
+	jsondocRecords, _ := jsondoc.RecordsFromCSV("examples/jsondoc_example.csv", &log)
+	for _, record := range jsondocRecords {
+		secret, _ := jsondoc.FromCSVRecord(record, &log)
+		logValues(&log, record, secret.Data, secret)
+	}
+	textfileRecords, _ := textfile.RecordsFromCSV("examples/textfile_example.csv", &log)
+	for _, record := range textfileRecords {
+		secret, _ := textfile.FromCSVRecord(record, &log)
+		logValues(&log, record, secret.Data, secret)
+	}
+
+	output := buf.String()
+	if !strings.Contains(output, tools.Redacted) {
+		t.Fatalf("log output has no redacted values:\n%s", output)
+	}
+	values := logStrings(t, output)
+	for _, secret := range secrets {
+		for _, value := range values {
+			if containsSecret(value, secret) {
+				t.Errorf("log output contains secret value %q in %q", secret, value)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package uploader
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/natemarks/secret-hoard/tools"
+)
+
+// ReportRecord is the report entry of one CSV record
+type ReportRecord struct {
+	Row          int          `json:"row"`
+	SecretID     string       `json:"secretId"`
+	ResourceType string       `json:"resourceType"`
+	Action       store.Action `json:"action,omitempty"`
+	VersionID    string       `json:"versionId,omitempty"`
+	Restored     bool         `json:"restored,omitempty"`
+	Retries      int          `json:"retries"`
+	Error        string       `json:"error,omitempty"`
+}
+
+// Report is the machine-readable result of an sh-upload run
+type Report struct {
+	File    string         `json:"file"`
+	Plan    bool           `json:"plan"`
+	Summary map[string]int `json:"summary"`
+	Records []ReportRecord `json:"records"`
+	Error   string         `json:"error,omitempty"` // set when the file couldn't be processed
+}
+
+// NewReport builds the report of a run. err is the error returned by CSVProcessor.Process
+func NewReport(cfg tools.Config, results Results, err error) Report {
+	report := Report{
+		File: cfg.FilePath,
+		Plan: cfg.Plan,
+		Summary: map[string]int{
+			string(store.ActionCreate):    results.Count(store.ActionCreate),
+			string(store.ActionUpdate):    results.Count(store.ActionUpdate),
+			string(store.ActionUnchanged): results.Count(store.ActionUnchanged),
+			string(store.ActionSkip):      results.Count(store.ActionSkip),
+			string(store.ActionRestore):   results.Count(store.ActionRestore),
+			string(store.ActionDelete):    results.Count(store.ActionDelete),
+			"restored":                    results.Restored(),
+			"failed":                      results.Failed(),
+			"retries":                     results.Retries(),
+		},
+		Records: []ReportRecord{},
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	for _, result := range results {
+		record := ReportRecord{
+			Row:          result.Row,
+			SecretID:     result.SecretID,
+			ResourceType: result.ResourceType,
+			Action:       result.Action,
+			VersionID:    result.VersionID,
+			Restored:     result.Restored,
+			Retries:      result.Retries,
+		}
+		if result.Err != nil {
+			record.Error = result.Err.Error()
+		}
+		report.Records = append(report.Records, record)
+	}
+	return report
+}
+
+// Write writes the report as indented JSON
+func (r Report) Write(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(content, '\n'), 0644)
+}
@@ -0,0 +1,193 @@
+// Package writer creates and updates secrets with their tags, replica regions and KMS key.
+// The Secret types of the resource packages and the copied secrets of sh-copy write through it
+package writer
+
+import (
+	"context"
+
+	"github.com/natemarks/secret-hoard/store"
+	"github.com/rs/zerolog"
+)
+
+// Exists returns true if the secret exists. Errors other than not found are returned. A
+// secret scheduled for deletion exists and the error wraps store.ErrScheduledForDeletion
+func Exists(options store.Options, secretID string, log *zerolog.Logger) (bool, error) {
+	st, err := store.OrDefault(options.Store)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to load secret store")
+		return false, err
+	}
+
+	// Describe the secret to check if it exists
+	exists, err := store.Exists(context.Background(), st, secretID)
+	if err != nil {
+		log.Error().Err(err).Msgf("error checking if secret exists: %s", secretID)
+		return exists, err
+	}
+	if !exists {
+		log.Debug().Msgf("secret does not exist: %s", secretID)
+		return false, nil
+	}
+	log.Debug().Msgf("secret exists: %s", secretID)
+	return true, nil
+}
+
+// Create creates the secret with the KMS key of the options and replicates it to their
+// replica regions
+func Create(options store.Options, secretID, value string, tags map[string]string, log *zerolog.Logger) (versionID string, err error) {
+	st, err := store.OrDefault(options.Store)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to load secret store")
+		return "", err
+	}
+	versionID, err = st.Create(context.Background(), secretID, value, tags, options.KmsKeyID)
+	if err != nil {
+		log.Error().Err(err).Msgf("error creating secret: %s", secretID)
+		return "", err
+	}
+	if len(options.ReplicaRegions) > 0 {
+		if err = st.ReplicateRegions(context.Background(), secretID, options.ReplicaRegions); err != nil {
+			log.Error().Err(err).Msgf("error replicating secret: %s", secretID)
+			return versionID, err
+		}
+	}
+	log.Info().Msgf("secret created successfully: %s", secretID)
+	return versionID, nil
+}
+
+// Restore cancels the scheduled deletion of the secret so it can be updated
+func Restore(options store.Options, secretID string, log *zerolog.Logger) error {
+	st, err := store.OrDefault(options.Store)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to load secret store")
+		return err
+	}
+	if err = st.Restore(context.Background(), secretID); err != nil {
+		log.Error().Err(err).Msgf("error restoring secret: %s", secretID)
+		return err
+	}
+	log.Info().Msgf("secret restored: %s", secretID)
+	return nil
+}
+
+// Update the secret. Nothing is written when the stored value, tags, replica regions and KMS
+// key already match. The action is update, unchanged or skip (overwrite is false) and
+// versionID is the resulting AWSCURRENT version
+func Update(options store.Options, secretID, value string, tags map[string]string, overwrite bool, log *zerolog.Logger) (action store.Action, versionID string, err error) {
+	st, err := store.OrDefault(options.Store)
+	if err != nil {
+		log.Error().Err(err).Msg("unable to load secret store")
+		return "", "", err
+	}
+	ctx := context.Background()
+
+	// Compare with the stored value and tags
+	change, err := store.PlanChange(ctx, st, secretID, value, tags, options.ReplicaRegions, options.KmsKeyID, overwrite)
+	if err != nil {
+		log.Error().Err(err).Msgf("error comparing secret with stored value: %s", secretID)
+		return "", "", err
+	}
+	switch change.Action {
+	case store.ActionUnchanged:
+		log.Info().Msgf("secret unchanged: %s", secretID)
+		return change.Action, change.VersionID, nil
+	case store.ActionSkip:
+		log.Debug().Msgf("overwrite is false, skipping update for %s", secretID)
+		return change.Action, change.VersionID, nil
+	}
+
+	// Encrypt the secret with the KMS key before the new value is stored
+	if change.KmsKeyID != "" {
+		if err = st.SetKMSKey(ctx, secretID, change.KmsKeyID); err != nil {
+			log.Error().Err(err).Msgf("error updating secret KMS key: %s", secretID)
+			return "", "", err
+		}
+	}
+
+	// Update the secret string value
+	versionID = change.VersionID
+	if len(change.Fields) > 0 {
+		versionID, err = st.Put(ctx, secretID, value)
+		if err != nil {
+			log.Error().Err(err).Msgf("error updating secret value: %s", secretID)
+			return "", "", err
+		}
+	}
+
+	// Update the secret tags
+	if len(change.Tags) > 0 {
+		err = st.Tag(ctx, secretID, tags)
+		if err != nil {
+			log.Error().Err(err).Msgf("error updating secret tags: %s", secretID)
+			return "", "", err
+		}
+	}
+
+	// Reconcile the replica regions
+	if len(change.Replicas) > 0 {
+		if err = store.ReconcileReplicas(ctx, st, secretID, options.ReplicaRegions); err != nil {
+			log.Error().Err(err).Msgf("error updating secret replicas: %s", secretID)
+			return "", "", err
+		}
+	}
+	log.Info().Msgf("secret update successfully: %s", secretID)
+	return store.ActionUpdate, versionID, nil
+}
+
+// Plan returns the change Create or Update would make without writing anything
+func Plan(options store.Options, secretID, value string, tags map[string]string, overwrite bool, log *zerolog.Logger) (change store.Change, err error) {
+	change.SecretID = secretID
+	st, err := store.OrDefault(options.Store)
+	if err != nil {
+		return change, err
+	}
+	change, err = store.PlanChange(context.Background(), st, secretID, value, tags, options.ReplicaRegions, options.KmsKeyID, overwrite)
+	if err != nil {
+		return change, err
+	}
+	log.Debug().Msgf("planned %s: %s", change.Action, change.SecretID)
+	return change, nil
+}
+
+// Secret is a secret whose value is written as is ex. a secret copied from another account
+type Secret struct {
+	Name     string            // secret ID
+	Value    string            // secret string
+	Metadata map[string]string // tags
+	store.Options
+}
+
+// SecretID returns the secret id
+func (s Secret) SecretID() string {
+	return s.Name
+}
+
+// Tags returns the tags of the secret
+func (s Secret) Tags() map[string]string {
+	return s.Metadata
+}
+
+// Exists returns true if the secret exists. See Exists
+func (s Secret) Exists(log *zerolog.Logger) (bool, error) {
+	return Exists(s.Options, s.Name, log)
+}
+
+// Create the secret
+func (s Secret) Create(log *zerolog.Logger) (versionID string, err error) {
+	return Create(s.Options, s.Name, s.Value, s.Metadata, log)
+}
+
+// Restore cancels the scheduled deletion of the secret so it can be updated
+func (s Secret) Restore(log *zerolog.Logger) error {
+	return Restore(s.Options, s.Name, log)
+}
+
+// Update the secret. See Update
+func (s Secret) Update(overwrite bool, log *zerolog.Logger) (action store.Action, versionID string, err error) {
+	return Update(s.Options, s.Name, s.Value, s.Metadata, overwrite, log)
+}
+
+// Plan returns the change Create or Update would make without writing anything
+func (s Secret) Plan(overwrite bool, log *zerolog.Logger) (change store.Change, err error) {
+	return Plan(s.Options, s.Name, s.Value, s.Metadata, overwrite, log)
+}